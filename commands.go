@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlphaWong/Stars/services"
+)
+
+const (
+	ExitOK = 0
+	// ExitChanged is returned by diff and -dry-run when the output would change
+	ExitChanged = 1
+	ExitError   = 2
+	// ExitInvalid is returned by validate-template when it finds problems
+	ExitInvalid = 1
+	// ExitUnhealthy is returned by health when it finds issues
+	ExitUnhealthy = 1
+
+	DefaultCommand = "render"
+	// DefaultExportFile is read by import and written by export
+	DefaultExportFile = "stars.json"
+	// DefaultImportInterval spaces out the requests of import, star and
+	// unstar
+	DefaultImportInterval = time.Second
+
+	ErrorUnknownCommand = "Unknown command %q"
+	ErrorDryRunFormat   = "The %s format writes a directory and cannot be diffed"
+	ErrorDryRunSplit    = "-split writes a directory and cannot be diffed"
+	ErrorGroupBy        = "Unknown group-by %q"
+	ErrorNoRepos        = "No repository to %s, give owner/repo arguments, -file or -filter"
+	ErrorNotConfirmed   = "Aborted, nothing to %s"
+	ErrorNoHistory      = "Missing -history, the database render -history records into"
+)
+
+// stdin answers the confirmation of the star and unstar commands
+var stdin io.Reader = os.Stdin
+
+// Command runs a sub command with the arguments following its name
+type Command func(config *BaseConfig, args []string, stdout io.Writer) (int, error)
+
+var commands = map[string]Command{
+	DefaultCommand:      renderCommand,
+	"daemon":            watchCommand,
+	"diff":              diffCommand,
+	"export":            exportCommand,
+	"health":            healthCommand,
+	"history":           historyCommand,
+	"import":            importCommand,
+	"serve":             serveCommand,
+	"star":              starCommand(services.ActionStar),
+	"unstar":            starCommand(services.ActionUnstar),
+	"stats":             statsCommand,
+	"templates":         templatesCommand,
+	"validate-template": validateTemplateCommand,
+	"watch":             watchCommand,
+}
+
+// execute dispatches "stars [command] [flags]", the render command is the
+// default when the first argument is a flag.
+func execute(config *BaseConfig, args []string, stdout io.Writer) int {
+	name := DefaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		log.Printf(ErrorUnknownCommand, name)
+		return ExitError
+	}
+	code, err := command(config, args, stdout)
+	if err != nil {
+		log.Println(err)
+		return ExitError
+	}
+	return code
+}
+
+func renderCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet(DefaultCommand, config)
+	flags.BoolVar(&config.DryRun, "dry-run", config.DryRun, "print what would change instead of writing the output")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+	if config.DryRun {
+		return dryRun(config, stdout)
+	}
+	return ExitOK, run(config)
+}
+
+func diffCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	if err := newFlagSet("diff", config).Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+	return dryRun(config, stdout)
+}
+
+// dryRun renders into memory and prints a unified diff against the current
+// output followed by the starred, unstarred and moved repositories.
+func dryRun(config *BaseConfig, stdout io.Writer) (int, error) {
+	if config.SplitDir != "" {
+		return ExitError, errors.New(ErrorDryRunSplit)
+	}
+	results, err := fetch(config)
+	if err != nil {
+		return ExitError, err
+	}
+	printer, err := newPrinter(config)
+	if err != nil {
+		return ExitError, err
+	}
+	renderer, ok := printer.(services.Renderer)
+	if !ok {
+		return ExitError, fmt.Errorf(ErrorDryRunFormat, config.Format)
+	}
+	rendered, err := renderer.Render(results)
+	if err != nil {
+		return ExitError, err
+	}
+	previous, err := ioutil.ReadFile(config.OutputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return ExitError, err
+	}
+
+	fmt.Fprint(stdout, services.UnifiedDiff("a/"+config.OutputPath, "b/"+config.OutputPath, previous, rendered))
+	semantic := services.DiffSnapshots(
+		services.ParseOutputSnapshot(previous),
+		services.ParseOutputSnapshot(rendered),
+	)
+	fmt.Fprint(stdout, semantic.String())
+
+	if bytes.Equal(previous, rendered) {
+		return ExitOK, nil
+	}
+	return ExitChanged, nil
+}
+
+// templatesCommand lists the embedded template packs
+func templatesCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	packs, err := services.TemplatePacks(templatePacks)
+	if err != nil {
+		return ExitError, err
+	}
+	for _, pack := range packs {
+		fmt.Fprintln(stdout, pack)
+	}
+	return ExitOK, nil
+}
+
+// validateTemplateCommand checks the pack and template overrides against
+// the embedded sample dataset, without calling Github.
+func validateTemplateCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	if err := newFlagSet("validate-template", config).Parse(args); err != nil {
+		return ExitError, err
+	}
+	key, ok := services.GroupKeys[config.GroupBy]
+	if !ok {
+		return ExitError, fmt.Errorf(ErrorGroupBy, config.GroupBy)
+	}
+	var sample services.UserStarredRepositories
+	if err := json.Unmarshal(sampleStars, &sample); err != nil {
+		return ExitError, err
+	}
+
+	tpl, err := parseTemplate(config)
+	if err != nil {
+		fmt.Fprintln(stdout, strings.TrimPrefix(err.Error(), "template: "))
+		return ExitInvalid, nil
+	}
+	problems := services.ValidateTemplate(tpl, services.Repositories2Slice(sample, key))
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	if len(problems) > 0 {
+		return ExitInvalid, nil
+	}
+	fmt.Fprintln(stdout, "ok")
+	return ExitOK, nil
+}
+
+// statsCommand prints a summary of the starred repositories as a table or
+// as JSON.
+func statsCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("stats", config)
+	asJSON := flags.Bool("json", false, "print the stats as JSON")
+	top := flags.Int("top", services.DefaultStatsTop, "number of top owners")
+	staleAfter := flags.Duration("stale", services.DefaultStaleAfter, "time without a push after which a repository is stale")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+
+	repositories, err := fetchRepositories(config)
+	if err != nil {
+		return ExitError, err
+	}
+	stats := services.ComputeStats(repositories, *top, *staleAfter)
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return ExitOK, encoder.Encode(stats)
+	}
+	return ExitOK, stats.WriteTable(stdout)
+}
+
+// healthCommand reports archived, disabled and stale repositories, and the
+// repositories of the current output which were renamed or are gone.
+func healthCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("health", config)
+	asJSON := flags.Bool("json", false, "print the issues as JSON")
+	staleAfter := flags.Duration("stale", services.DefaultStaleAfter, "time without a push after which a repository is stale")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+
+	fetcher, err := newFetcher(config)
+	if err != nil {
+		return ExitError, err
+	}
+	repositories, err := fetcher.GetUsersRepositories()
+	if err != nil {
+		return ExitError, err
+	}
+	issues := services.CheckHealth(repositories, *staleAfter)
+
+	previous, err := ioutil.ReadFile(config.OutputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return ExitError, err
+	}
+	var fullNames []string
+	for fullName := range services.ParseOutputSnapshot(previous) {
+		fullNames = append(fullNames, fullName)
+	}
+	sort.Strings(fullNames)
+	gone, err := fetcher.CheckGone(fullNames, repositories)
+	if err != nil {
+		return ExitError, err
+	}
+	issues = append(issues, gone...)
+	services.SortHealthIssues(issues)
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(issues)
+	} else {
+		err = services.WriteHealthTable(stdout, issues)
+	}
+	if err != nil {
+		return ExitError, err
+	}
+	if len(issues) > 0 {
+		return ExitUnhealthy, nil
+	}
+	return ExitOK, nil
+}
+
+// starCommand stars or unstars the repositories given as arguments, read
+// from -file or matching -filter among the current stars, after asking for
+// confirmation unless -yes or -dry-run is set.
+func starCommand(action string) Command {
+	return func(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+		flags := newFlagSet(action, config)
+		yes := flags.Bool("yes", false, "do not ask for confirmation")
+		file := flags.String("file", "", "file listing one owner/repo per line")
+		filter := flags.String("filter", "", "comma separated field=value matched against the current stars, e.g. health=archived")
+		interval := flags.Duration("interval", DefaultImportInterval, "minimum time between two requests")
+		flags.BoolVar(&config.DryRun, "dry-run", config.DryRun, "print what would be done without calling Github")
+		if err := flags.Parse(args); err != nil {
+			return ExitError, err
+		}
+		validConfig(config)
+
+		fullNames, err := starTargets(config, flags.Args(), *file, *filter)
+		if err != nil {
+			return ExitError, err
+		}
+		if len(fullNames) == 0 {
+			return ExitError, fmt.Errorf(ErrorNoRepos, action)
+		}
+		if !*yes && !config.DryRun {
+			noun, _ := services.Pluralize(len(fullNames), "repository", "repositories")
+			fmt.Fprintf(stdout, "%s\n%s %d %s? [y/N] ", strings.Join(fullNames, "\n"), action, len(fullNames), noun)
+			answer, _ := bufio.NewReader(stdin).ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+				return ExitError, fmt.Errorf(ErrorNotConfirmed, action)
+			}
+		}
+
+		starrer, err := services.NewGitHubStarrer(
+			services.WithStarrerToken(config.Token),
+			services.WithStarrerInterval(*interval),
+		)
+		if err != nil {
+			return ExitError, err
+		}
+		results := services.ApplyStars(starrer, action, fullNames, config.DryRun)
+		if err := services.WriteStarResults(stdout, results); err != nil {
+			return ExitError, err
+		}
+		for _, result := range results {
+			if result.Result == services.StarResultFailed {
+				return ExitError, nil
+			}
+		}
+		return ExitOK, nil
+	}
+}
+
+// starTargets collects the repositories of the star and unstar commands,
+// each one once and in the order given
+func starTargets(config *BaseConfig, args []string, file string, filter string) ([]string, error) {
+	var fullNames []string
+	for _, arg := range args {
+		fullName, err := services.ParseRepoName(arg)
+		if err != nil {
+			return nil, err
+		}
+		fullNames = append(fullNames, fullName)
+	}
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		names, err := services.ReadRepoNames(f)
+		if err != nil {
+			return nil, err
+		}
+		fullNames = append(fullNames, names...)
+	}
+	if filter != "" {
+		repositories, err := fetchRepositories(config)
+		if err != nil {
+			return nil, err
+		}
+		if repositories, err = services.FilterRepos(filter, repositories); err != nil {
+			return nil, err
+		}
+		for _, v := range repositories {
+			fullNames = append(fullNames, v.FullName)
+		}
+	}
+
+	seen := make(map[string]bool, len(fullNames))
+	unique := fullNames[:0]
+	for _, fullName := range fullNames {
+		if key := strings.ToLower(fullName); !seen[key] {
+			seen[key] = true
+			unique = append(unique, fullName)
+		}
+	}
+	return unique, nil
+}
+
+// exportCommand writes the starred repositories along with their star
+// lists to a portable file
+func exportCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("export", config)
+	file := flags.String("file", DefaultExportFile, "export file to write")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+
+	repositories, err := fetchRepositories(config)
+	if err != nil {
+		return ExitError, err
+	}
+	starrer, err := services.NewGitHubStarrer(services.WithStarrerToken(config.Token))
+	if err != nil {
+		return ExitError, err
+	}
+	starLists, err := starrer.StarLists(config.UserName)
+	if err != nil {
+		return ExitError, err
+	}
+	export := services.NewStarsExport(config.UserName, repositories, starLists)
+	if err := services.WriteStarsExport(*file, export); err != nil {
+		return ExitError, err
+	}
+	fmt.Fprintf(stdout, "exported %d stars of %s to %s\n", len(export.Stars), config.UserName, *file)
+	return ExitOK, nil
+}
+
+// importCommand stars the repositories of an export file as the owner of
+// TOKEN, run it again after an interruption to resume.
+func importCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("import", config)
+	file := flags.String("file", DefaultExportFile, "export file to read")
+	statePath := flags.String("state", "", "file recording the progress of the import, defaults to the export file with a .state suffix")
+	interval := flags.Duration("interval", DefaultImportInterval, "minimum time between two requests")
+	flags.BoolVar(&config.DryRun, "dry-run", config.DryRun, "list the repositories import would star")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+	if *statePath == "" {
+		*statePath = *file + ".state"
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return ExitError, err
+	}
+	export, err := services.ReadStarsExport(f)
+	f.Close()
+	if err != nil {
+		return ExitError, err
+	}
+	state, err := services.OpenImportState(*statePath)
+	if err != nil {
+		return ExitError, err
+	}
+	defer state.Close()
+
+	if config.DryRun {
+		for _, star := range export.Stars {
+			if state.Result(star.FullName) == "" || state.Result(star.FullName) == services.ImportFailed {
+				fmt.Fprintln(stdout, star.FullName)
+			}
+		}
+		return ExitOK, nil
+	}
+
+	starrer, err := services.NewGitHubStarrer(
+		services.WithStarrerToken(config.Token),
+		services.WithStarrerInterval(*interval),
+	)
+	if err != nil {
+		return ExitError, err
+	}
+	summary, err := services.ImportStars(starrer, export, state)
+	if err != nil {
+		return ExitError, err
+	}
+	if err := services.WriteImportSummary(stdout, summary); err != nil {
+		return ExitError, err
+	}
+	if len(summary.Failed) > 0 {
+		return ExitError, nil
+	}
+	return ExitOK, nil
+}
+
+// historyCommand lists the recorded runs, or shows what changed between two
+// runs, the timeline of one repository or the share of a language.
+func historyCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("history", config)
+	from := flags.Int("from", 0, "run to compare from, defaults to the one before -to")
+	to := flags.Int("to", 0, "run to compare to, defaults to the latest")
+	repo := flags.String("repo", "", "print the timeline of owner/repo")
+	language := flags.String("language", "", "print the share of the language in every run")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	if config.HistoryPath == "" {
+		return ExitError, errors.New(ErrorNoHistory)
+	}
+
+	history, err := services.OpenHistory(config.HistoryPath)
+	if err != nil {
+		return ExitError, err
+	}
+	defer history.Close()
+	runs, err := history.Runs()
+	if err != nil {
+		return ExitError, err
+	}
+
+	switch {
+	case *repo != "":
+		return ExitOK, services.WriteTimeline(stdout, services.Timeline(runs, *repo))
+	case *language != "":
+		return ExitOK, services.WriteLanguageShare(stdout, runs, *language)
+	case *from == 0 && *to == 0:
+		return ExitOK, services.WriteHistoryRuns(stdout, runs)
+	}
+
+	if *to == 0 {
+		*to = len(runs)
+	}
+	if *from == 0 {
+		*from = *to - 1
+	}
+	for _, n := range []int{*from, *to} {
+		if n < 1 || n > len(runs) {
+			return ExitError, fmt.Errorf(services.ErrorHistoryRun, n, len(runs))
+		}
+	}
+	return ExitOK, services.WriteHistoryDelta(stdout, services.DiffRuns(runs[*from-1], runs[*to-1]))
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaWong/Stars/services"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+// registerStarredResponders serves mock_data page 1 and 2 as the starred
+// repositories of alphawong.
+func registerStarredResponders(require *require.Assertions) {
+	response1Path, err := filepath.Abs("./mock_data/page_1.json")
+	require.NoError(err)
+	response2Path, err := filepath.Abs("./mock_data/page_2.json")
+	require.NoError(err)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=1&per_page=100",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, httpmock.File(response1Path).String())
+			resp.Header.Set("link", `<https://api.github.com/user/5622516/starred?page=2>; rel="next", <https://api.github.com/user/5622516/starred?page=2>; rel="last"`)
+			return resp, nil
+		},
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=2&per_page=100",
+		httpmock.NewJsonResponderOrPanic(http.StatusOK, httpmock.File(response2Path)),
+	)
+}
+
+// writeTestTemplate writes the README table template used by the command tests
+func writeTestTemplate(require *require.Assertions) string {
+	tmpfile, err := ioutil.TempFile("", "tpl.*.md")
+	require.NoError(err)
+	_, err = tmpfile.WriteString(`{{define "layout"}}# Result
+Language|⭐️|Repos
+---|---|---
+{{ range . }}{{.Language}}|{{.Stars}}|{{.Items}}
+{{end}}{{end}}`)
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+	return tmpfile.Name()
+}
+
+func TestExecuteWithUnknownCommand(t *testing.T) {
+	require := require.New(t)
+	require.Equal(ExitError, execute(boot(), []string{"explode"}, ioutil.Discard))
+}
+
+func TestExecuteWithInvalidFlag(t *testing.T) {
+	require := require.New(t)
+	require.Equal(ExitError, execute(boot(), []string{"-no-such-flag"}, ioutil.Discard))
+}
+
+func TestDiffCommand(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	templatePath := writeTestTemplate(require)
+	defer os.Remove(templatePath)
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	defer os.Remove(outputFile.Name())
+	_, err = outputFile.WriteString("# Result\nLanguage|⭐️|Repos\n---|---|---\n" +
+		"Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n" +
+		"Rust|1|[ [a/gone](https://github.com/a/gone) ]\n")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"diff", "-template", templatePath, "-output", outputFile.Name()}, &stdout)
+	require.Equal(ExitChanged, code)
+	require.Contains(stdout.String(), "-Rust|1|[ [a/gone](https://github.com/a/gone) ]\n")
+	require.Contains(stdout.String(), "+JavaScript|1|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]\n")
+	require.Contains(stdout.String(), "+ stefanwuthrich/cached-google-places (JavaScript)\n- a/gone (Rust)\n1 starred, 1 unstarred, 0 moved\n")
+
+	// the output file is left untouched
+	actual, err := ioutil.ReadFile(outputFile.Name())
+	require.NoError(err)
+	require.Contains(string(actual), "a/gone")
+
+	// once rendered, the dry run reports no change
+	code = execute(boot(), []string{"-template", templatePath, "-output", outputFile.Name()}, &stdout)
+	require.Equal(ExitOK, code)
+	stdout.Reset()
+	code = execute(boot(), []string{"-dry-run", "-template", templatePath, "-output", outputFile.Name()}, &stdout)
+	require.Equal(ExitOK, code)
+	require.Equal("0 starred, 0 unstarred, 0 moved\n", stdout.String())
+}
+
+func TestDiffCommandWithHTMLFormat(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	code := execute(boot(), []string{"diff", "-format", "html", "-output", os.TempDir()}, ioutil.Discard)
+	require.Equal(ExitError, code)
+}
+
+func TestDiffCommandWithSplitDir(t *testing.T) {
+	require := require.New(t)
+	code := execute(boot(), []string{"diff", "-split", "stars", "-output", "README.md"}, ioutil.Discard)
+	require.Equal(ExitError, code)
+}
+
+func TestTemplatesCommand(t *testing.T) {
+	require := require.New(t)
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"templates"}, &stdout))
+	require.Equal("awesome\ndetails\nlist\nstarred\ntable\n", stdout.String())
+}
+
+func TestRenderCommandWithPack(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+	defer os.Remove(outputFile.Name())
+
+	// run from another directory to make sure nothing is read from ./template
+	wd, err := os.Getwd()
+	require.NoError(err)
+	require.NoError(os.Chdir(os.TempDir()))
+	defer os.Chdir(wd)
+
+	code := execute(boot(), []string{"-pack", "table", "-output", outputFile.Name()}, ioutil.Discard)
+	require.Equal(ExitOK, code)
+	actual, err := ioutil.ReadFile(outputFile.Name())
+	require.NoError(err)
+	require.Equal("# Stars\n\nLanguage|⭐️|Repos\n---|---|---\n"+
+		"Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n"+
+		"JavaScript|1|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]\n", string(actual))
+}
+
+func TestValidateTemplateCommand(t *testing.T) {
+	require := require.New(t)
+	os.Unsetenv("TOKEN")
+	defer os.Setenv("TOKEN", "TOKEN")
+
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"validate-template", "-pack", "awesome"}, &stdout))
+	require.Equal("ok\n", stdout.String())
+
+	tmpfile, err := ioutil.TempFile("", "tpl.*.md")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(`{{define "repo"}}{{.FullNam}}{{end}}`)
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+
+	stdout.Reset()
+	require.Equal(ExitInvalid, execute(boot(), []string{"validate-template", "-pack", "list", "-template", tmpfile.Name()}, &stdout))
+	require.Equal(filepath.Base(tmpfile.Name())+":1:19: unknown field FullNam in type services.Repository\n", stdout.String())
+
+	require.NoError(ioutil.WriteFile(tmpfile.Name(), []byte(`{{define "layout"}}{{range}}{{end}}`), 0644))
+	stdout.Reset()
+	require.Equal(ExitInvalid, execute(boot(), []string{"validate-template", "-template", tmpfile.Name()}, &stdout))
+	require.Contains(stdout.String(), filepath.Base(tmpfile.Name())+":1: missing value for range")
+}
+
+func TestStatsCommand(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"stats", "-json", "-top", "1"}, &stdout))
+	var stats services.Stats
+	require.NoError(json.Unmarshal([]byte(stdout.String()), &stats))
+	require.Equal(2, stats.Total)
+	require.Len(stats.TopOwners, 1)
+	require.Len(stats.Owners, 2)
+
+	stdout.Reset()
+	require.Equal(ExitOK, execute(boot(), []string{"stats"}, &stdout))
+	require.Contains(stdout.String(), "Total")
+	require.Contains(stdout.String(), "Top owner")
+}
+
+func TestHealthCommand(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repos/a/gone",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	defer os.Remove(outputFile.Name())
+	_, err = outputFile.WriteString("Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n" +
+		"Rust|1|[ [a/gone](https://github.com/a/gone) ]\n")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"health", "-json", "-output", outputFile.Name()}, &stdout)
+	require.Equal(ExitUnhealthy, code)
+	var issues []services.HealthIssue
+	require.NoError(json.Unmarshal([]byte(stdout.String()), &issues))
+	require.Equal(services.HealthMissing, issues[0].Kind)
+	require.Equal("a/gone", issues[0].FullName)
+}
+
+func TestHealthCommandWithDefaultPack(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	defer os.Remove(outputFile.Name())
+	require.NoError(outputFile.Close())
+	require.Equal(ExitOK, execute(boot(), []string{"-output", outputFile.Name()}, ioutil.Discard))
+
+	// the links of the default layout, e.g. settings/tokens, are not repositories
+	var stdout strings.Builder
+	code := execute(boot(), []string{"health", "-json", "-output", outputFile.Name()}, &stdout)
+	require.NotEqual(ExitError, code)
+	var issues []services.HealthIssue
+	require.NoError(json.Unmarshal([]byte(stdout.String()), &issues))
+	for _, issue := range issues {
+		require.NotEqual(services.HealthMissing, issue.Kind, issue.FullName)
+	}
+	require.Zero(httpmock.GetCallCountInfo()["GET https://api.github.com/repos/settings/tokens"])
+}
+
+func TestParseTemplateWithCleanup(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-pack", "list", "-cleanup"}))
+	tpl, err := parseTemplate(config)
+	require.NoError(err)
+	require.NotNil(tpl.Lookup("cleanup"))
+	require.Contains(tpl.Lookup("cleanup").Tree.Root.String(), "Needs cleanup")
+}
+
+func TestUnstarCommand(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodDelete, "https://api.github.com/user/starred/a/b",
+		httpmock.NewStringResponder(http.StatusNoContent, ""))
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"unstar", "-yes", "a/b", "https://github.com/A/B"}, &stdout)
+	require.Equal(ExitOK, code)
+	require.Equal(1, httpmock.GetTotalCallCount())
+	require.Contains(stdout.String(), "done    unstar  a/b")
+}
+
+func TestUnstarCommandWithInterval(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodDelete, `=~^https://api\.github\.com/user/starred/`,
+		httpmock.NewStringResponder(http.StatusNoContent, ""))
+
+	started := time.Now()
+	code := execute(boot(), []string{"unstar", "-yes", "-interval", "50ms", "a/b", "c/d", "e/f"}, ioutil.Discard)
+	require.Equal(ExitOK, code)
+	require.Equal(3, httpmock.GetTotalCallCount())
+	require.GreaterOrEqual(int64(time.Since(started)), int64(100*time.Millisecond))
+}
+
+func TestUnstarCommandWithoutConfirmation(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer func() {
+		stdin = os.Stdin
+	}()
+	stdin = strings.NewReader("n\n")
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"unstar", "a/b"}, &stdout)
+	require.Equal(ExitError, code)
+	require.Equal("a/b\nunstar 1 repository? [y/N] ", stdout.String())
+	require.Equal(0, httpmock.GetTotalCallCount())
+}
+
+func TestStarCommandWithFailure(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer func() {
+		stdin = os.Stdin
+	}()
+	stdin = strings.NewReader("y\n")
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/a/b",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+
+	listFile, err := ioutil.TempFile("", "repos.*.txt")
+	require.NoError(err)
+	defer os.Remove(listFile.Name())
+	_, err = listFile.WriteString("# to star\na/b\n")
+	require.NoError(err)
+	require.NoError(listFile.Close())
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"star", "-file", listFile.Name()}, &stdout)
+	require.Equal(ExitError, code)
+	require.Contains(stdout.String(), "failed  star    a/b         Repository not found")
+}
+
+func TestUnstarCommandWithFilterAndDryRun(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	var stdout strings.Builder
+	code := execute(boot(), []string{"unstar", "-dry-run", "-filter", "language=Go"}, &stdout)
+	require.Equal(ExitOK, code)
+	require.Contains(stdout.String(), "dry-run  unstar  victorspringer/http-cache")
+	require.Equal(0, httpmock.GetCallCountInfo()["DELETE https://api.github.com/user/starred/victorspringer/http-cache"])
+}
+
+func TestUnstarCommandWithoutRepositories(t *testing.T) {
+	require := require.New(t)
+	require.Equal(ExitError, execute(boot(), []string{"unstar", "-yes"}, ioutil.Discard))
+}
+
+func TestExportAndImportCommands(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	var updated []string
+	httpmock.RegisterResponder(http.MethodPost, services.GithubGraphQLURI,
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Query     string                 `json:"query"`
+				Variables map[string]interface{} `json:"variables"`
+			}
+			require.NoError(json.NewDecoder(req.Body).Decode(&body))
+			switch {
+			case strings.Contains(body.Query, "user(login"):
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"user": {"lists": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"id": "LIST1", "name": "go", "items": {
+						"pageInfo": {"hasNextPage": false},
+						"nodes": [{"nameWithOwner": "victorspringer/http-cache"}]
+					}}]
+				}}}}`), nil
+			case strings.Contains(body.Query, "viewer"):
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"viewer": {"lists": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"id": "LIST2", "name": "go"}]
+				}}}}`), nil
+			case strings.Contains(body.Query, "updateUserListsForItem"):
+				updated = append(updated, body.Variables["item"].(string))
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {}}`), nil
+			}
+			// deleted rather than renamed
+			return httpmock.NewStringResponse(http.StatusOK, `{"data": {"node": null}}`), nil
+		})
+
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	exportPath := filepath.Join(dir, "stars.json")
+
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"export", "-file", exportPath}, &stdout))
+	require.Equal("exported 2 stars of alphawong to "+exportPath+"\n", stdout.String())
+	f, err := os.Open(exportPath)
+	require.NoError(err)
+	export, err := services.ReadStarsExport(f)
+	f.Close()
+	require.NoError(err)
+	lists := make(map[string][]string)
+	nodeIDs := make(map[string]string)
+	for _, star := range export.Stars {
+		lists[star.FullName] = star.Lists
+		nodeIDs[star.FullName] = star.NodeID
+	}
+	require.Equal(map[string][]string{
+		"victorspringer/http-cache":           {"go"},
+		"stefanwuthrich/cached-google-places": {},
+	}, lists)
+
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/victorspringer/http-cache",
+		httpmock.NewStringResponder(http.StatusNoContent, ""))
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/stefanwuthrich/cached-google-places",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+
+	stdout.Reset()
+	require.Equal(ExitOK, execute(boot(), []string{"import", "-file", exportPath, "-interval", "0"}, &stdout))
+	require.Equal("1 starred, 0 already starred, 1 missing, 0 failed\nmissing stefanwuthrich/cached-google-places\n", stdout.String())
+	require.Equal([]string{nodeIDs["victorspringer/http-cache"]}, updated)
+
+	// resuming sends nothing more
+	stdout.Reset()
+	httpmock.ZeroCallCounters()
+	require.Equal(ExitOK, execute(boot(), []string{"import", "-file", exportPath, "-interval", "0"}, &stdout))
+	require.Equal("0 starred, 1 already starred, 1 missing, 0 failed\nmissing stefanwuthrich/cached-google-places\n", stdout.String())
+	require.Equal(0, httpmock.GetTotalCallCount())
+}
+
+func TestHistoryCommand(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	templatePath := writeTestTemplate(require)
+	defer os.Remove(templatePath)
+	historyPath := filepath.Join(dir, "stars.db")
+
+	for i := 0; i < 2; i++ {
+		code := execute(boot(), []string{"-template", templatePath, "-output", filepath.Join(dir, "out.md"), "-history", historyPath}, ioutil.Discard)
+		require.Equal(ExitOK, code)
+	}
+
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"history", "-history", historyPath}, &stdout))
+	require.Equal(3, strings.Count(stdout.String(), "\n"))
+
+	stdout.Reset()
+	require.Equal(ExitOK, execute(boot(), []string{"history", "-history", historyPath, "-to", "2"}, &stdout))
+	require.Equal("0 added, 0 removed, 0 renamed\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(ExitOK, execute(boot(), []string{"history", "-history", historyPath, "-repo", "victorspringer/http-cache"}, &stdout))
+	require.Contains(stdout.String(), "starred  victorspringer/http-cache")
+
+	stdout.Reset()
+	require.Equal(ExitOK, execute(boot(), []string{"history", "-history", historyPath, "-language", "Go"}, &stdout))
+	require.Contains(stdout.String(), "50.0%")
+
+	require.Equal(ExitError, execute(boot(), []string{"history", "-history", historyPath, "-from", "3"}, ioutil.Discard))
+	require.Equal(ExitError, execute(boot(), []string{"history"}, ioutil.Discard))
+}
+
+func TestValidateTemplateCommandWithChanges(t *testing.T) {
+	require := require.New(t)
+	tmpfile, err := ioutil.TempFile("", "tpl.*.md")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(`{{define "footer"}}{{with changes}}{{.Summary}}{{range .Starred}}{{.Group}}{{end}}{{end}}{{end}}`)
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+
+	var stdout strings.Builder
+	require.Equal(ExitOK, execute(boot(), []string{"validate-template", "-pack", "list", "-template", tmpfile.Name()}, &stdout))
+	require.Equal("ok\n", stdout.String())
+}
+
+func TestRenderCommandWithCommitAndPush(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+
+	dir, err := ioutil.TempDir("", "repo")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	remote, err := ioutil.TempDir("", "remote")
+	require.NoError(err)
+	defer os.RemoveAll(remote)
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	git(dir, "init", "--quiet", "-b", "main")
+	git(remote, "init", "--quiet", "--bare")
+
+	args := []string{
+		"-pack", "table",
+		"-output", filepath.Join(dir, "README.md"),
+		"-commit", dir,
+		"-commit-author", "Stars Bot <bot@example.com>",
+		"-commit-message", "{{.Stats.Total}} stars of {{.UserName}}",
+		"-push", remote,
+	}
+	require.Equal(ExitOK, execute(boot(), args, ioutil.Discard))
+	require.Equal("2 stars of alphawong", git(dir, "log", "-1", "--format=%s"))
+	require.Equal(git(dir, "rev-parse", "HEAD"), git(remote, "rev-parse", "refs/heads/main"))
+
+	// the output did not change, nothing is committed
+	require.Equal(ExitOK, execute(boot(), args, ioutil.Discard))
+	require.Equal("1", git(dir, "rev-list", "--count", "HEAD"))
+	require.Equal("", git(dir, "status", "--porcelain"))
+}
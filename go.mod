@@ -3,8 +3,9 @@ module github.com/AlphaWong/Stars
 go 1.16
 
 require (
-	github.com/go-playground/validator/v10 v10.4.1 // indirect
+	github.com/go-playground/validator/v10 v10.4.1
 	github.com/gogo/protobuf v1.3.2
 	github.com/jarcoal/httpmock v1.0.8
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.6
 )
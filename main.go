@@ -1,12 +1,18 @@
 package main
 
 import (
+	"embed"
+	"flag"
 	"fmt"
+	"io/fs"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/AlphaWong/Stars/services"
 	"github.com/go-playground/validator/v10"
@@ -14,17 +20,94 @@ import (
 
 var validate *validator.Validate
 
+//go:embed template/*.md
+var templateFS embed.FS
+
+// templatePacks holds the embedded template packs, template/<pack>.md
+var templatePacks, _ = fs.Sub(templateFS, "template")
+
+// sampleStars is the sample dataset templates are validated against
+//
+//go:embed mock_data/page_total.json
+var sampleStars []byte
+
+const (
+	FormatTemplate  = "template"
+	FormatCSV       = "csv"
+	FormatTSV       = "tsv"
+	FormatHTML      = "html"
+	FormatAtom      = services.FeedAtom
+	FormatRSS       = services.FeedRSS
+	FormatBookmarks = "bookmarks"
+	FormatOPML      = "opml"
+	FormatInject    = "inject"
+	FormatMarkdown  = "markdown"
+	// FormatChanges and FormatChangesJSON write the changes since the
+	// previous snapshot alone
+	FormatChanges     = "changes"
+	FormatChangesJSON = "changes-json"
+
+	DefaultTemplatePack = "starred"
+	DefaultOutputPath   = "./out.md"
+	// DefaultHTMLOutputDir replaces DefaultOutputPath for the html format,
+	// which writes a directory
+	DefaultHTMLOutputDir = "./site"
+
+	ErrorHTMLOutput = "The html format writes a directory, -output %q ends in .md"
+)
+
 type BaseConfig struct {
-	Token        string `validate:"required"`
-	UserName     string `validate:"required"`
-	BaseTemplate string `validate:"required"`
+	Token    string `validate:"required"`
+	UserName string `validate:"required"`
+	// BaseTemplate optionally names a template file or directory whose
+	// blocks override the ones of TemplatePack
+	BaseTemplate string
+	TemplatePack string `validate:"required"`
 	OutputPath   string `validate:"required"`
-	mu           sync.Mutex
+	GroupBy      string `validate:"oneof=language topic owner"`
+	Format       string `validate:"oneof=template csv tsv html atom rss bookmarks opml inject markdown changes changes-json"`
+	// Columns and PerGroup only apply to the csv and tsv formats
+	Columns  []string
+	PerGroup bool
+	// FeedLimit caps the number of entries of the atom and rss formats
+	FeedLimit int `validate:"gte=0"`
+	// SkipArchived leaves archived repositories out of the opml format
+	SkipArchived bool
+	// SplitDir makes the template format write one file per group into it,
+	// OutputPath becomes an index linking to them
+	SplitDir string
+	// Cleanup adds a needs cleanup section to the packs, see
+	// services.CleanupSection
+	Cleanup bool
+	// Charts writes svg charts next to the template output
+	Charts bool
+	// Collapse folds markdown groups larger than it into <details>, 0 never
+	Collapse int `validate:"gte=0"`
+	// HistoryPath is the bolt database render records every star set into
+	// and the history command reads, empty disables it
+	HistoryPath string
+	// SincePath is the previous output changes are computed against when
+	// there is no history, it defaults to OutputPath
+	SincePath string
+	// CommitDir is the git repository the output is committed into after
+	// every render, empty disables committing
+	CommitDir string
+	// CommitAuthor, CommitBranch and CommitMessage configure the commit, see
+	// services.GitCommitter
+	CommitAuthor  string
+	CommitBranch  string
+	CommitMessage string
+	// PushRemote is pushed the commit branch once the output is committed,
+	// empty never pushes
+	PushRemote string
+	// DryRun prints what would change instead of writing the output
+	DryRun bool
+	mu     sync.Mutex
 }
 
 func main() {
 	config := boot()
-	run(config)
+	os.Exit(execute(config, os.Args[1:], os.Stdout))
 }
 
 func boot() (config *BaseConfig) {
@@ -35,14 +118,69 @@ func boot() (config *BaseConfig) {
 	config = &BaseConfig{
 		Token:        token,
 		UserName:     userName,
-		BaseTemplate: "./template/starred.md",
-		OutputPath:   "./out.md",
+		BaseTemplate: "",
+		TemplatePack: DefaultTemplatePack,
+		OutputPath:   DefaultOutputPath,
+		GroupBy:      "language",
+		Format:       FormatTemplate,
+		Columns:      services.CSVColumns,
+		FeedLimit:    services.DefaultFeedLimit,
+		// the commit message summarises the stats of the stars
+		CommitMessage: services.DefaultCommitMessage,
 	}
-	// ensure the config is valid
-	validConfig(config)
+	// commands validate the config once their flags are parsed
 	return
 }
 
+// listFlag is a comma separated flag value, empty items are dropped so that
+// an empty value leaves the list empty
+type listFlag []string
+
+func (self *listFlag) String() string {
+	return strings.Join(*self, ",")
+}
+
+func (self *listFlag) Set(value string) error {
+	*self = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*self = append(*self, item)
+		}
+	}
+	return nil
+}
+
+func parseFlags(config *BaseConfig, args []string) error {
+	return newFlagSet("stars", config).Parse(args)
+}
+
+// newFlagSet registers the flags shared by every command onto config
+func newFlagSet(name string, config *BaseConfig) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&config.UserName, "user", config.UserName, "Github user name")
+	flags.StringVar(&config.TemplatePack, "pack", config.TemplatePack, "embedded template pack, see the templates command")
+	flags.StringVar(&config.BaseTemplate, "template", config.BaseTemplate, "template file or directory overriding blocks of the pack")
+	flags.StringVar(&config.OutputPath, "output", config.OutputPath, "output file, output directory for the html format (./site by default) or the file to inject into")
+	flags.StringVar(&config.Format, "format", config.Format, "output format: template, csv, tsv, html, atom, rss, bookmarks, opml, inject, markdown, changes or changes-json")
+	flags.StringVar(&config.GroupBy, "group-by", config.GroupBy, "group repositories by language, topic or owner")
+	flags.Var((*listFlag)(&config.Columns), "columns", "comma separated csv columns")
+	flags.BoolVar(&config.PerGroup, "per-group", config.PerGroup, "print one csv row per group instead of per repo")
+	flags.IntVar(&config.FeedLimit, "limit", config.FeedLimit, "number of feed entries, 0 for all")
+	flags.BoolVar(&config.SkipArchived, "skip-archived", config.SkipArchived, "leave archived repositories out of the opml")
+	flags.StringVar(&config.SplitDir, "split", config.SplitDir, "write one file per group into this directory, next to an index at -output, with the group and index templates")
+	flags.StringVar(&config.HistoryPath, "history", config.HistoryPath, "bolt database recording the stars of every run, see the history command")
+	flags.StringVar(&config.SincePath, "since", config.SincePath, "previous output to compute changes against when there is no -history, defaults to -output")
+	flags.StringVar(&config.CommitDir, "commit", config.CommitDir, "git repository to commit the output into when it changed")
+	flags.StringVar(&config.CommitAuthor, "commit-author", config.CommitAuthor, "author of the commit as Name <email>, defaults to the git configuration")
+	flags.StringVar(&config.CommitBranch, "commit-branch", config.CommitBranch, "branch to commit onto, defaults to the checked out one")
+	flags.StringVar(&config.CommitMessage, "commit-message", config.CommitMessage, "template of the commit message, given .UserName, .At and .Stats")
+	flags.StringVar(&config.PushRemote, "push", config.PushRemote, "remote to push the commit branch to after committing")
+	flags.BoolVar(&config.Cleanup, "cleanup", config.Cleanup, "add a needs cleanup section listing archived, disabled and stale repositories")
+	flags.BoolVar(&config.Charts, "charts", config.Charts, "write svg charts into a charts directory next to the template output")
+	flags.IntVar(&config.Collapse, "collapse", config.Collapse, "fold markdown groups with more repositories into <details>, 0 never folds")
+	return flags
+}
+
 func validConfig(config *BaseConfig) {
 	validate = validator.New()
 	err := validate.Struct(config)
@@ -54,27 +192,244 @@ func validConfig(config *BaseConfig) {
 	}
 }
 
-func run(config *BaseConfig) {
-	fetcher, err := services.NewGitHubFetcher(
-		services.WithToken(config.Token),
-		services.WithUserName(config.UserName),
-	)
+func run(config *BaseConfig) error {
+	repositories, err := fetchRepositories(config)
+	if nil != err {
+		return err
+	}
+	results := services.Repositories2Slice(repositories, services.GroupKeys[config.GroupBy])
+
+	// the printer takes its previous snapshot before this run is recorded
+	printer, err := newPrinter(config)
 	if nil != err {
-		fmt.Print(err.Error())
-		return
+		return err
 	}
-	results := fetcher.GetUsersStars()
+	if config.HistoryPath != "" {
+		if err := recordHistory(config, repositories); err != nil {
+			return err
+		}
+	}
+
+	if err := printer.PrintSlice(results); err != nil {
+		return err
+	}
+	return commitOutput(config, repositories)
+}
 
-	baseTemplatePath, _ := filepath.Abs(config.BaseTemplate)
-	outputPath, _ := filepath.Abs(config.OutputPath)
-	printer, err := services.NewTplPrinter(
-		services.WithBaseTemplate(template.ParseFiles(baseTemplatePath)),
-		services.WithOutputPath(outputPath),
+// commitOutput commits the files written by the printer into CommitDir,
+// nothing when they did not change, then pushes to PushRemote
+func commitOutput(config *BaseConfig, repositories services.UserStarredRepositories) error {
+	if config.CommitDir == "" {
+		return nil
+	}
+	committer, err := services.NewGitCommitter(
+		services.WithGitDir(config.CommitDir),
+		services.WithGitAuthor(config.CommitAuthor),
+		services.WithGitBranch(config.CommitBranch),
+		services.WithGitMessage(config.CommitMessage),
 	)
+	if err != nil {
+		return err
+	}
+	_, err = committer.Commit(outputPaths(config), services.CommitData{
+		UserName: config.UserName,
+		At:       time.Now(),
+		Stats:    services.ComputeStats(repositories, services.DefaultStatsTop, services.DefaultStaleAfter),
+	})
+	if err != nil || config.PushRemote == "" {
+		return err
+	}
+	// pushing when nothing was committed catches up after a failed push
+	return committer.Push(config.PushRemote)
+}
+
+// outputPaths are the files and directories the printer writes
+func outputPaths(config *BaseConfig) []string {
+	outputPath := absOutputPath(config)
+	paths := []string{outputPath}
+	if config.SplitDir != "" {
+		paths = append(paths, filepath.Join(filepath.Dir(outputPath), config.SplitDir))
+	}
+	if config.Charts {
+		paths = append(paths, filepath.Join(filepath.Dir(outputPath), services.ChartsDir))
+	}
+	return paths
+}
+
+func fetch(config *BaseConfig) ([]services.MarkDownRow, error) {
+	fetcher, err := newFetcher(config)
 	if nil != err {
-		fmt.Print(err.Error())
-		return
+		return nil, err
 	}
+	return fetcher.GetUsersStars()
+}
+
+// previousSnapshot is the latest recorded run when there is a history,
+// otherwise the repositories of the previous output. It is nil when there
+// is neither.
+func previousSnapshot(config *BaseConfig) (map[string]string, error) {
+	if config.HistoryPath != "" {
+		history, err := services.OpenHistory(config.HistoryPath)
+		if err != nil {
+			return nil, err
+		}
+		defer history.Close()
+		runs, err := history.Runs()
+		if err != nil || len(runs) == 0 {
+			return nil, err
+		}
+		return runs[len(runs)-1].Snapshot(config.GroupBy), nil
+	}
+	sincePath := config.SincePath
+	if sincePath == "" {
+		// a changes output or a split index does not list the repositories
+		if config.Format == FormatChanges || config.Format == FormatChangesJSON || config.SplitDir != "" {
+			return nil, nil
+		}
+		sincePath = config.OutputPath
+	}
+	previous, err := ioutil.ReadFile(sincePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return services.ParseOutputSnapshot(previous), nil
+}
+
+func recordHistory(config *BaseConfig, repositories services.UserStarredRepositories) error {
+	history, err := services.OpenHistory(config.HistoryPath)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+	return history.Record(time.Now(), config.GroupBy, repositories)
+}
+
+func fetchRepositories(config *BaseConfig) (services.UserStarredRepositories, error) {
+	fetcher, err := newFetcher(config)
+	if nil != err {
+		return nil, err
+	}
+	return fetcher.GetUsersRepositories()
+}
+
+func newFetcher(config *BaseConfig) (*services.GitHubFetcher, error) {
+	return services.NewGitHubFetcher(
+		services.WithToken(config.Token),
+		services.WithUserName(config.UserName),
+		services.WithGroupKey(services.GroupKeys[config.GroupBy]),
+	)
+}
+
+// absOutputPath is the absolute -output, the html format defaults to
+// DefaultHTMLOutputDir
+func absOutputPath(config *BaseConfig) string {
+	outputPath := config.OutputPath
+	if config.Format == FormatHTML && outputPath == DefaultOutputPath {
+		outputPath = DefaultHTMLOutputDir
+	}
+	outputPath, _ = filepath.Abs(outputPath)
+	return outputPath
+}
 
-	printer.PrintSlice(results)
+func newPrinter(config *BaseConfig) (services.Printer, error) {
+	outputPath := absOutputPath(config)
+	switch config.Format {
+	case FormatCSV, FormatTSV:
+		comma := ','
+		if config.Format == FormatTSV {
+			comma = '\t'
+		}
+		columns := config.Columns
+		if len(columns) == 0 {
+			// -columns ""
+			columns = services.CSVColumns
+		}
+		return services.NewCSVPrinter(
+			services.WithCSVColumns(columns),
+			services.WithCSVComma(comma),
+			services.WithCSVPerGroup(config.PerGroup),
+			services.WithCSVOutputPath(outputPath),
+		)
+	case FormatHTML:
+		if filepath.Ext(outputPath) == ".md" {
+			return nil, fmt.Errorf(ErrorHTMLOutput, config.OutputPath)
+		}
+		return services.NewHTMLPrinter(
+			services.WithHTMLOutputDir(outputPath),
+		)
+	case FormatAtom, FormatRSS:
+		return services.NewFeedPrinter(
+			services.WithFeedFormat(config.Format),
+			services.WithFeedUserName(config.UserName),
+			services.WithFeedLimit(config.FeedLimit),
+			services.WithFeedOutputPath(outputPath),
+		)
+	case FormatBookmarks:
+		return services.NewBookmarksPrinter(
+			services.WithBookmarksOutputPath(outputPath),
+		)
+	case FormatOPML:
+		return services.NewOPMLPrinter(
+			services.WithOPMLSkipArchived(config.SkipArchived),
+			services.WithOPMLOutputPath(outputPath),
+		)
+	case FormatMarkdown:
+		return services.NewMarkdownPrinter(
+			services.WithMarkdownCollapse(config.Collapse),
+			services.WithMarkdownOutputPath(outputPath),
+		)
+	case FormatChanges, FormatChangesJSON:
+		previous, err := previousSnapshot(config)
+		if err != nil {
+			return nil, err
+		}
+		format := services.ChangesMarkdown
+		if config.Format == FormatChangesJSON {
+			format = services.ChangesJSON
+		}
+		return services.NewChangesPrinter(
+			services.WithChangesFormat(format),
+			services.WithChangesPrevious(previous),
+			services.WithChangesOutputPath(outputPath),
+		)
+	case FormatInject:
+		return services.NewInjectPrinter(
+			services.WithInjectTemplate(parseTemplate(config)),
+			services.WithInjectTargetPath(outputPath),
+		)
+	default:
+		previous, err := previousSnapshot(config)
+		if err != nil {
+			return nil, err
+		}
+		return services.NewTplPrinter(
+			services.WithBaseTemplate(parseTemplate(config)),
+			services.WithOutputPath(outputPath),
+			services.WithSplitDir(config.SplitDir),
+			services.WithCharts(config.Charts),
+			services.WithPreviousSnapshot(previous),
+		)
+	}
+}
+
+func parseTemplate(config *BaseConfig) (*template.Template, error) {
+	var overrides []string
+	if config.BaseTemplate != "" {
+		baseTemplatePath, _ := filepath.Abs(config.BaseTemplate)
+		overrides = append(overrides, baseTemplatePath)
+	}
+	tpl, err := services.ParseTemplatePack(templatePacks, config.TemplatePack)
+	if err != nil {
+		return nil, err
+	}
+	// parsed before the overrides so that they can still redefine it
+	if config.Cleanup {
+		if tpl, err = tpl.Parse(services.CleanupSection); err != nil {
+			return nil, err
+		}
+	}
+	return services.ParseTemplateOverrides(tpl, overrides...)
 }
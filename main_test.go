@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/AlphaWong/Stars/services"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/require"
 )
@@ -81,7 +83,8 @@ Language|⭐️|Repos
 	defer config.mu.Unlock()
 	config.BaseTemplate = tmpfile.Name()
 	config.OutputPath = outputFile.Name()
-	run(config)
+	err = run(config)
+	require.NoError(err)
 
 	info := httpmock.GetCallCountInfo()
 	log.Println(info)
@@ -90,3 +93,197 @@ Language|⭐️|Repos
 	require.NoError(err)
 	require.Equal("# Result\nLanguage|⭐️|Repos\n---|---|---\nGo|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\nJavaScript|1|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]\n", string(actual))
 }
+
+func TestParseFlags(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	err := parseFlags(config, []string{
+		"-user", "octocat",
+		"-format", "tsv",
+		"-columns", "full_name,stars",
+		"-per-group",
+		"-output", "out.tsv",
+	})
+	require.NoError(err)
+	require.Equal("octocat", config.UserName)
+	require.Equal(FormatTSV, config.Format)
+	require.Equal([]string{"full_name", "stars"}, config.Columns)
+	require.True(config.PerGroup)
+	require.Equal("out.tsv", config.OutputPath)
+	require.NotPanics(func() {
+		validConfig(config)
+	})
+}
+
+func TestParseFlagsWithEmptyColumns(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "csv", "-columns", "", "-output", "out.csv"}))
+	require.Empty(config.Columns)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.Equal(services.CSVColumns, printer.(*services.CSVPrinter).Columns)
+
+	require.NoError(parseFlags(config, []string{"-columns", " full_name, ,stars,"}))
+	require.Equal([]string{"full_name", "stars"}, config.Columns)
+}
+
+func TestValidConfigWithUnknownFormat(t *testing.T) {
+	require := require.New(t)
+	require.Panics(func() {
+		config := boot()
+		config.Format = "pdf"
+		validConfig(config)
+	})
+}
+
+func TestNewPrinterWithCSVFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	config.Format = FormatCSV
+	config.Columns = []string{"unknown"}
+	printer, err := newPrinter(config)
+	require.Error(err)
+	require.Nil(printer)
+
+	config.Columns = []string{"full_name"}
+	printer, err = newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.CSVPrinter{}, printer)
+}
+
+func TestNewPrinterWithHTMLFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	config.Format = FormatHTML
+	config.OutputPath = "./site"
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.HTMLPrinter{}, printer)
+}
+
+func TestNewPrinterWithHTMLFormatAndDefaultOutput(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	config.Format = FormatHTML
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	outputDir, _ := filepath.Abs(DefaultHTMLOutputDir)
+	require.Equal(outputDir, printer.(*services.HTMLPrinter).OutputDir)
+
+	config.OutputPath = "./stars.md"
+	_, err = newPrinter(config)
+	require.EqualError(err, fmt.Sprintf(ErrorHTMLOutput, "./stars.md"))
+}
+
+func TestNewPrinterWithFeedFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "rss", "-limit", "10", "-output", "stars.xml"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.FeedPrinter{}, printer)
+	require.Equal(10, printer.(*services.FeedPrinter).Limit)
+	require.Equal(services.FeedRSS, printer.(*services.FeedPrinter).Format)
+}
+
+func TestNewPrinterWithBookmarksFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "bookmarks", "-group-by", "topic", "-output", "bookmarks.html"}))
+	validConfig(config)
+	require.Equal("topic", config.GroupBy)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.BookmarksPrinter{}, printer)
+}
+
+func TestValidConfigWithUnknownGroupBy(t *testing.T) {
+	require := require.New(t)
+	require.Panics(func() {
+		config := boot()
+		config.GroupBy = "license"
+		validConfig(config)
+	})
+}
+
+func TestNewPrinterWithOPMLFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "opml", "-skip-archived", "-output", "stars.opml"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.OPMLPrinter{}, printer)
+	require.True(printer.(*services.OPMLPrinter).SkipArchived)
+}
+
+func TestNewPrinterWithInjectFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "inject", "-output", "README.md"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.InjectPrinter{}, printer)
+}
+
+func TestNewPrinterWithMarkdownFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "markdown", "-collapse", "20", "-output", "README.md"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.MarkdownPrinter{}, printer)
+	require.Equal(20, printer.(*services.MarkdownPrinter).Collapse)
+}
+
+func TestNewPrinterWithSplitDir(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-split", "stars", "-output", "README.md"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.Equal("stars", printer.(*services.TplPrinter).SplitDir)
+}
+
+func TestNewPrinterWithCharts(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-charts", "-output", "README.md"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.True(printer.(*services.TplPrinter).Charts)
+}
+
+func TestNewPrinterWithChangesFormat(t *testing.T) {
+	require := require.New(t)
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "changes-json", "-output", "changes.json"}))
+	validConfig(config)
+	printer, err := newPrinter(config)
+	require.NoError(err)
+	require.IsType(&services.ChangesPrinter{}, printer)
+	require.Equal(services.ChangesJSON, printer.(*services.ChangesPrinter).Format)
+	require.Nil(printer.(*services.ChangesPrinter).Previous)
+}
+
+func TestPreviousSnapshotWithSince(t *testing.T) {
+	require := require.New(t)
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	defer os.Remove(outputFile.Name())
+	_, err = outputFile.WriteString("Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+
+	config := boot()
+	require.NoError(parseFlags(config, []string{"-format", "changes", "-since", outputFile.Name(), "-output", "changes.md"}))
+	previous, err := previousSnapshot(config)
+	require.NoError(err)
+	require.Equal(map[string]string{"victorspringer/http-cache": "Go"}, previous)
+}
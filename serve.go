@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AlphaWong/Stars/services"
+)
+
+const (
+	DefaultServeAddr = ":8080"
+	// DefaultServeRefresh is how often serve refetches the stars
+	DefaultServeRefresh = 15 * time.Minute
+	// DefaultShutdownTimeout is how long serve waits for requests in flight
+	DefaultShutdownTimeout = 10 * time.Second
+
+	ErrorServeFormat = "The %s format cannot be served"
+	ErrorServeGroup  = "Unknown group %q"
+	ErrorNotReady    = "Not ready, the stars have not been fetched yet"
+
+	// MaxWebhookBody is the largest payload GitHub delivers
+	MaxWebhookBody = 25 << 20
+)
+
+// serveContentTypes are the content types of the formats served at the page
+// route, the html format serves a whole site and types every file by its
+// extension
+var serveContentTypes = map[string]string{
+	FormatTemplate:    "text/markdown; charset=utf-8",
+	FormatMarkdown:    "text/markdown; charset=utf-8",
+	FormatChanges:     "text/markdown; charset=utf-8",
+	FormatChangesJSON: "application/json",
+	FormatCSV:         "text/csv; charset=utf-8",
+	FormatTSV:         "text/tab-separated-values; charset=utf-8",
+	FormatAtom:        "application/atom+xml",
+	FormatRSS:         "application/rss+xml",
+	FormatBookmarks:   "text/html; charset=utf-8",
+	FormatOPML:        "text/x-opml; charset=utf-8",
+}
+
+// serveRoutes are the paths serve answers on
+type serveRoutes struct {
+	Page    string
+	JSON    string
+	Health  string
+	Ready   string
+	Webhook string
+	Metrics string
+}
+
+// serveJSON is the body of the json route
+type serveJSON struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Total     int          `json:"total"`
+	Groups    []serveGroup `json:"groups"`
+}
+
+type serveGroup struct {
+	Group string                           `json:"group"`
+	Repos services.UserStarredRepositories `json:"repos"`
+}
+
+// starsServer renders the stars on request from an in-memory copy which is
+// refreshed in the background
+type starsServer struct {
+	config  *BaseConfig
+	printer services.Printer
+	routes  serveRoutes
+	// secret verifies the webhook deliveries, empty disables the webhook
+	secret []byte
+	// write writes the output whenever the stars change
+	write  bool
+	logger *runLogger
+
+	mu           sync.RWMutex
+	repositories services.UserStarredRepositories
+	fetchedAt    time.Time
+	// version counts the updates of repositories
+	version int
+
+	// render serializes rendering, printers are not safe for concurrent use
+	render sync.Mutex
+	// output serializes writing and committing the output, written is the
+	// version last written
+	output  sync.Mutex
+	written int
+}
+
+// serveCommand serves the rendered output, the stars as JSON and health and
+// readiness checks until SIGTERM or SIGINT. The page and json routes take
+// the group, filter and sort query parameters, e.g.
+// /?group=owner&filter=language=Go&sort=-stars
+//
+// With a webhook secret, star and watch events sent by the user to the
+// webhook route update the stars without waiting for the next refresh.
+func serveCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("serve", config)
+	addr := flags.String("addr", DefaultServeAddr, "address to listen on")
+	refresh := flags.Duration("refresh", DefaultServeRefresh, "how often the stars are refetched")
+	maxBackoff := flags.Duration("max-backoff", DefaultMaxBackoff, "longest wait between failed refreshes")
+	routes := serveRoutes{}
+	flags.StringVar(&routes.Page, "route", "/", "route of the rendered output, the html site is served below it")
+	flags.StringVar(&routes.JSON, "json-route", "/stars.json", "route of the stars as JSON")
+	flags.StringVar(&routes.Health, "health-route", "/healthz", "route answering while the server runs")
+	flags.StringVar(&routes.Ready, "ready-route", "/readyz", "route answering once the stars are fetched")
+	flags.StringVar(&routes.Webhook, "webhook-route", "/webhook", "route receiving the star and watch events of a GitHub webhook")
+	flags.StringVar(&routes.Metrics, "metrics-route", MetricsRoute, "route of the Prometheus metrics, empty serves none")
+	secret := flags.String("webhook-secret", os.Getenv("WEBHOOK_SECRET"), "secret of the GitHub webhook, the webhook route is off without it")
+	write := flags.Bool("write", false, "also write the output after every refresh and webhook event")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+	if _, err := services.NewIntervalSchedule(*refresh); err != nil {
+		return ExitError, err
+	}
+
+	logger := newRunLogger(stdout)
+	server, err := newStarsServer(config, routes,
+		withWebhookSecret(*secret),
+		withWrite(*write),
+		withLogger(logger),
+	)
+	if err != nil {
+		return ExitError, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Log("info", "listen", map[string]interface{}{"addr": *addr})
+	httpServer := &http.Server{Addr: *addr, Handler: server.Handler()}
+	failed := make(chan error, 1)
+	go func() {
+		failed <- httpServer.ListenAndServe()
+	}()
+	go server.refreshLoop(ctx, *refresh, *maxBackoff)
+
+	select {
+	case err := <-failed:
+		return ExitError, err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdown); err != nil {
+		return ExitError, err
+	}
+	return ExitOK, logger.Log("info", "shutdown", nil)
+}
+
+type starsServerOption func(*starsServer)
+
+func withWebhookSecret(secret string) starsServerOption {
+	return func(server *starsServer) {
+		server.secret = []byte(secret)
+	}
+}
+
+func withWrite(write bool) starsServerOption {
+	return func(server *starsServer) {
+		server.write = write
+	}
+}
+
+func withLogger(logger *runLogger) starsServerOption {
+	return func(server *starsServer) {
+		server.logger = logger
+	}
+}
+
+func newStarsServer(config *BaseConfig, routes serveRoutes, setters ...starsServerOption) (*starsServer, error) {
+	printer, err := newPrinter(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := printer.(services.Renderer); !ok && config.Format != FormatHTML {
+		return nil, fmt.Errorf(ErrorServeFormat, config.Format)
+	}
+	server := &starsServer{
+		config:  config,
+		printer: printer,
+		routes:  routes,
+		logger:  newRunLogger(ioutil.Discard),
+	}
+	for _, setter := range setters {
+		setter(server)
+	}
+	return server, nil
+}
+
+func (self *starsServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	page := self.routes.Page
+	if self.config.Format == FormatHTML && !strings.HasSuffix(page, "/") {
+		// the site links its pages relative to the route
+		mux.Handle(page, http.RedirectHandler(page+"/", http.StatusMovedPermanently))
+		page += "/"
+	}
+	mux.HandleFunc(page, self.servePage)
+	mux.HandleFunc(self.routes.JSON, self.serveJSON)
+	mux.HandleFunc(self.routes.Health, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc(self.routes.Ready, func(w http.ResponseWriter, r *http.Request) {
+		if _, fetchedAt := self.snapshot(); fetchedAt.IsZero() {
+			http.Error(w, ErrorNotReady, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+	if len(self.secret) > 0 {
+		mux.HandleFunc(self.routes.Webhook, self.serveWebhook)
+	}
+	if self.routes.Metrics != "" {
+		mux.Handle(self.routes.Metrics, services.DefaultMetrics)
+	}
+	return mux
+}
+
+// refresh refetches the stars, the previous ones keep being served when it
+// fails
+func (self *starsServer) refresh() (int, error) {
+	repositories, err := fetchWithinRateLimit(self.config)
+	if err != nil {
+		return 0, err
+	}
+	return len(repositories), self.update(func(services.UserStarredRepositories) services.UserStarredRepositories {
+		return repositories
+	})
+}
+
+// update replaces the stars with the result of apply and writes the output
+// when asked to. The stars are swapped under the lock alone, requests are
+// served while the output is written and committed.
+func (self *starsServer) update(apply func(services.UserStarredRepositories) services.UserStarredRepositories) error {
+	self.mu.Lock()
+	self.repositories = apply(self.repositories)
+	self.fetchedAt = time.Now()
+	self.version++
+	repositories := self.repositories
+	self.mu.Unlock()
+	if !self.write {
+		services.ObserveGroups(services.Repositories2Slice(repositories, services.GroupKeys[self.config.GroupBy]))
+		return nil
+	}
+	return self.writeOutput()
+}
+
+// writeOutput records, writes and commits the latest stars. When two
+// updates race the first one to get here writes the stars of both, the
+// second one has nothing left to write.
+func (self *starsServer) writeOutput() error {
+	self.output.Lock()
+	defer self.output.Unlock()
+	self.mu.RLock()
+	repositories, version := self.repositories, self.version
+	self.mu.RUnlock()
+	if version == self.written {
+		return nil
+	}
+	if self.config.HistoryPath != "" {
+		if err := recordHistory(self.config, repositories); err != nil {
+			return err
+		}
+	}
+	results := services.Repositories2Slice(repositories, services.GroupKeys[self.config.GroupBy])
+	self.render.Lock()
+	err := services.InstrumentPrinter(self.printer).PrintSlice(results)
+	self.render.Unlock()
+	if err != nil {
+		return err
+	}
+	self.written = version
+	return commitOutput(self.config, repositories)
+}
+
+// refreshLoop refreshes right away then every interval until ctx is done,
+// backing off like watch after failures
+func (self *starsServer) refreshLoop(ctx context.Context, every time.Duration, maxBackoff time.Duration) {
+	failures := 0
+	for {
+		stars, err := self.refresh()
+		finished := time.Now()
+		next := finished.Add(every)
+		if err != nil {
+			failures++
+			services.DefaultMetrics.Add(services.MetricRunFailures, 1, "")
+			next = finished.Add(watchBackoff(failures, maxBackoff))
+			var limited *services.RateLimitError
+			if errors.As(err, &limited) && limited.Reset.After(next) {
+				next = limited.Reset
+			}
+			self.logger.Log("error", "refresh", map[string]interface{}{
+				"error":    err.Error(),
+				"failures": failures,
+				"next":     next.UTC().Format(time.RFC3339),
+			})
+		} else {
+			failures = 0
+			services.DefaultMetrics.Set(services.MetricLastSuccess, float64(finished.Unix()), "")
+			self.logger.Log("info", "refresh", map[string]interface{}{
+				"stars": stars,
+				"next":  next.UTC().Format(time.RFC3339),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-watchAfter(time.Until(next)):
+		}
+	}
+}
+
+func (self *starsServer) snapshot() (services.UserStarredRepositories, time.Time) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.repositories, self.fetchedAt
+}
+
+// query filters, sorts and groups the stars as asked by the group, filter
+// and sort query parameters. The status tells why it failed.
+func (self *starsServer) query(r *http.Request) ([]services.MarkDownRow, time.Time, int, error) {
+	repositories, fetchedAt := self.snapshot()
+	if fetchedAt.IsZero() {
+		return nil, fetchedAt, http.StatusServiceUnavailable, errors.New(ErrorNotReady)
+	}
+	values := r.URL.Query()
+	group := self.config.GroupBy
+	if value := values.Get("group"); value != "" {
+		group = value
+	}
+	key, ok := services.GroupKeys[group]
+	if !ok {
+		return nil, fetchedAt, http.StatusBadRequest, fmt.Errorf(ErrorServeGroup, group)
+	}
+	var err error
+	if filter := values.Get("filter"); filter != "" {
+		if repositories, err = services.FilterRepos(filter, repositories); err != nil {
+			return nil, fetchedAt, http.StatusBadRequest, err
+		}
+	}
+	if field := values.Get("sort"); field != "" {
+		if repositories, err = services.SortRepos(field, repositories); err != nil {
+			return nil, fetchedAt, http.StatusBadRequest, err
+		}
+	}
+	return services.Repositories2Slice(repositories, key), fetchedAt, http.StatusOK, nil
+}
+
+func (self *starsServer) servePage(w http.ResponseWriter, r *http.Request) {
+	name := ""
+	if self.config.Format == FormatHTML {
+		name = strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(self.routes.Page, "/")+"/")
+		if name == "" {
+			name = "index.html"
+		}
+	} else if r.URL.Path != self.routes.Page {
+		http.NotFound(w, r)
+		return
+	}
+
+	rows, _, status, err := self.query(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	self.render.Lock()
+	var body []byte
+	contentType := serveContentTypes[self.config.Format]
+	if htmlPrinter, ok := self.printer.(*services.HTMLPrinter); ok {
+		body, err = htmlPrinter.RenderFile(rows, name)
+		contentType = mime.TypeByExtension(path.Ext(name))
+	} else {
+		body, err = self.printer.(services.Renderer).Render(rows)
+	}
+	self.render.Unlock()
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+func (self *starsServer) serveJSON(w http.ResponseWriter, r *http.Request) {
+	rows, fetchedAt, status, err := self.query(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	body := serveJSON{FetchedAt: fetchedAt.UTC(), Groups: make([]serveGroup, 0, len(rows))}
+	for _, row := range rows {
+		body.Total += len(row.Repos)
+		body.Groups = append(body.Groups, serveGroup{Group: row.Language, Repos: row.Repos})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(body)
+}
+
+// serveWebhook applies the star and watch events the user sends, other
+// events and the events of other users are acknowledged and ignored
+func (self *starsServer) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxWebhookBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := services.VerifySignature(self.secret, body, r.Header.Get(services.SignatureHeader)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	name := r.Header.Get(services.EventHeader)
+	if name != services.EventStar && name != services.EventWatch {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignored %s\n", name)
+		return
+	}
+	event, err := services.ParseStarEvent(name, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !event.By(self.config.UserName) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignored %s by %s\n", name, event.Sender.Login)
+		return
+	}
+	// GitHub redelivers failed deliveries, the event applies once the stars
+	// are fetched
+	if _, fetchedAt := self.snapshot(); fetchedAt.IsZero() {
+		http.Error(w, ErrorNotReady, http.StatusServiceUnavailable)
+		return
+	}
+
+	err = self.update(func(repositories services.UserStarredRepositories) services.UserStarredRepositories {
+		return services.ApplyStarEvent(repositories, event)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	self.logger.Log("info", "webhook", map[string]interface{}{
+		"action":    event.Action,
+		"full_name": event.Repository.FullName,
+	})
+	fmt.Fprintf(w, "%s %s\n", event.Action, event.Repository.FullName)
+}
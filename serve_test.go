@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AlphaWong/Stars/services"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+var testServeRoutes = serveRoutes{Page: "/", JSON: "/stars.json", Health: "/healthz", Ready: "/readyz"}
+
+// registerRateLimitResponder serves a rate limit with plenty of requests left
+func registerRateLimitResponder() {
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		services.GithubRateLimitURI,
+		httpmock.NewStringResponder(http.StatusOK, `{"rate":{"limit":5000,"remaining":4999,"reset":1792368000}}`),
+	)
+}
+
+func serveRequest(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestStarsServer(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+
+	config := boot()
+	config.TemplatePack = "table"
+	server, err := newStarsServer(config, testServeRoutes)
+	require.NoError(err)
+	handler := server.Handler()
+
+	require.Equal(http.StatusOK, serveRequest(handler, "/healthz").Code)
+	require.Equal(http.StatusServiceUnavailable, serveRequest(handler, "/readyz").Code)
+	require.Equal(http.StatusServiceUnavailable, serveRequest(handler, "/").Code)
+
+	stars, err := server.refresh()
+	require.NoError(err)
+	require.Equal(2, stars)
+	require.Equal(http.StatusOK, serveRequest(handler, "/readyz").Code)
+
+	page := serveRequest(handler, "/")
+	require.Equal(http.StatusOK, page.Code)
+	require.Equal("text/markdown; charset=utf-8", page.Header().Get("Content-Type"))
+	require.Equal("# Stars\n\nLanguage|⭐️|Repos\n---|---|---\n"+
+		"Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n"+
+		"JavaScript|1|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]\n", page.Body.String())
+
+	page = serveRequest(handler, "/?filter=language=Go&group=owner")
+	require.Equal("# Stars\n\nLanguage|⭐️|Repos\n---|---|---\n"+
+		"victorspringer|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\n", page.Body.String())
+
+	var body serveJSON
+	response := serveRequest(handler, "/stars.json?group=owner&sort=-name")
+	require.Equal("application/json", response.Header().Get("Content-Type"))
+	require.NoError(json.Unmarshal(response.Body.Bytes(), &body))
+	require.Equal(2, body.Total)
+	require.Len(body.Groups, 2)
+	require.Equal("stefanwuthrich", body.Groups[0].Group)
+	require.Equal("stefanwuthrich/cached-google-places", body.Groups[0].Repos[0].FullName)
+
+	require.Equal(http.StatusBadRequest, serveRequest(handler, "/stars.json?group=planet").Code)
+	require.Equal(http.StatusBadRequest, serveRequest(handler, "/?sort=color").Code)
+	require.Equal(http.StatusBadRequest, serveRequest(handler, "/?filter=nonsense").Code)
+	require.Equal(http.StatusNotFound, serveRequest(handler, "/other").Code)
+}
+
+// blockingPrinter waits for release before printing
+type blockingPrinter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (self *blockingPrinter) PrintSlice(markDownRows []services.MarkDownRow) error {
+	close(self.started)
+	<-self.release
+	return nil
+}
+
+func TestStarsServerServesWhileWriting(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+
+	config := boot()
+	config.TemplatePack = "table"
+	server, err := newStarsServer(config, testServeRoutes, withWrite(true))
+	require.NoError(err)
+	printer := &blockingPrinter{started: make(chan struct{}), release: make(chan struct{})}
+	server.printer = printer
+	handler := server.Handler()
+
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := server.refresh()
+		refreshed <- err
+	}()
+	<-printer.started
+	require.Equal(http.StatusOK, serveRequest(handler, "/readyz").Code)
+	var body serveJSON
+	require.NoError(json.Unmarshal(serveRequest(handler, "/stars.json").Body.Bytes(), &body))
+	require.Equal(2, body.Total)
+
+	close(printer.release)
+	require.NoError(<-refreshed)
+	// nothing changed since the stars were written
+	require.NoError(server.writeOutput())
+}
+
+func TestStarsServerWithHTMLFormat(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+
+	dir, err := ioutil.TempDir("", "site")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	config := boot()
+	config.Format = FormatHTML
+	config.OutputPath = dir
+	server, err := newStarsServer(config, serveRoutes{Page: "/stars", JSON: "/stars.json", Health: "/healthz", Ready: "/readyz"})
+	require.NoError(err)
+	_, err = server.refresh()
+	require.NoError(err)
+	handler := server.Handler()
+
+	require.Equal(http.StatusMovedPermanently, serveRequest(handler, "/stars").Code)
+	index := serveRequest(handler, "/stars/")
+	require.Equal(http.StatusOK, index.Code)
+	require.Equal("text/html; charset=utf-8", index.Header().Get("Content-Type"))
+	require.Contains(index.Body.String(), `<a href="groups/go.html">Go</a>`)
+	require.Equal(http.StatusOK, serveRequest(handler, "/stars/groups/go.html").Code)
+	require.Equal(http.StatusOK, serveRequest(handler, "/stars/style.css").Code)
+	require.Equal(http.StatusNotFound, serveRequest(handler, "/stars/groups/rust.html").Code)
+
+	// nothing is written to the output directory
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Empty(files)
+}
+
+func postWebhook(require *require.Assertions, handler http.Handler, secret string, event string, file string) *httptest.ResponseRecorder {
+	body, err := ioutil.ReadFile(file)
+	require.NoError(err)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set(services.EventHeader, event)
+	req.Header.Set(services.SignatureHeader, services.Signature([]byte(secret), body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestStarsServerWebhook(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+	defer os.Remove(outputFile.Name())
+	config := boot()
+	config.TemplatePack = "table"
+	config.OutputPath = outputFile.Name()
+	routes := testServeRoutes
+	routes.Webhook = "/webhook"
+	server, err := newStarsServer(config, routes, withWebhookSecret("secret"), withWrite(true))
+	require.NoError(err)
+	handler := server.Handler()
+
+	response := postWebhook(require, handler, "secret", services.EventStar, "./mock_data/webhook_star_created.json")
+	require.Equal(http.StatusServiceUnavailable, response.Code)
+	_, err = server.refresh()
+	require.NoError(err)
+
+	response = postWebhook(require, handler, "guess", services.EventStar, "./mock_data/webhook_star_created.json")
+	require.Equal(http.StatusUnauthorized, response.Code)
+	response = postWebhook(require, handler, "secret", "ping", "./mock_data/webhook_star_created.json")
+	require.Equal(http.StatusAccepted, response.Code)
+	require.Equal(http.StatusMethodNotAllowed, serveRequest(handler, "/webhook").Code)
+
+	response = postWebhook(require, handler, "secret", services.EventStar, "./mock_data/webhook_star_created.json")
+	require.Equal(http.StatusOK, response.Code)
+	require.Equal("created microsoft/vscode\n", response.Body.String())
+	response = postWebhook(require, handler, "secret", services.EventStar, "./mock_data/webhook_star_deleted.json")
+	require.Equal(http.StatusOK, response.Code)
+	require.Equal("deleted victorspringer/http-cache\n", response.Body.String())
+
+	expected := "# Stars\n\nLanguage|⭐️|Repos\n---|---|---\n" +
+		"JavaScript|1|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]\n" +
+		"TypeScript|1|[ [microsoft/vscode](https://github.com/microsoft/vscode) ]\n"
+	require.Equal(expected, serveRequest(handler, "/").Body.String())
+	actual, err := ioutil.ReadFile(outputFile.Name())
+	require.NoError(err)
+	require.Equal(expected, string(actual))
+
+	// the events of other users are about the repositories they star
+	body, err := ioutil.ReadFile("./mock_data/webhook_watch_started.json")
+	require.NoError(err)
+	body = bytes.Replace(body, []byte(`"login": "AlphaWong"`), []byte(`"login": "octocat"`), 1)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set(services.EventHeader, services.EventWatch)
+	req.Header.Set(services.SignatureHeader, services.Signature([]byte("secret"), body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	require.Equal(http.StatusAccepted, recorder.Code)
+	require.Equal("ignored watch by octocat\n", recorder.Body.String())
+}
+
+func TestStarsServerWithoutWebhookSecret(t *testing.T) {
+	require := require.New(t)
+	routes := testServeRoutes
+	routes.Webhook = "/webhook"
+	server, err := newStarsServer(boot(), routes)
+	require.NoError(err)
+	response := postWebhook(require, server.Handler(), "", services.EventStar, "./mock_data/webhook_star_created.json")
+	require.Equal(http.StatusNotFound, response.Code)
+}
+
+func TestStarsServerMetrics(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+
+	routes := testServeRoutes
+	routes.Metrics = MetricsRoute
+	server, err := newStarsServer(boot(), routes)
+	require.NoError(err)
+	pages := services.DefaultMetrics.Value(services.MetricPages, "")
+	_, err = server.refresh()
+	require.NoError(err)
+	require.Equal(pages+2, services.DefaultMetrics.Value(services.MetricPages, ""))
+
+	response := serveRequest(server.Handler(), "/metrics")
+	require.Equal(http.StatusOK, response.Code)
+	require.Equal(services.MetricsContentType, response.Header().Get("Content-Type"))
+	for _, line := range []string{
+		"# TYPE stars_fetch_duration_seconds histogram",
+		`stars_group_stars{group="Go"} 1`,
+		`stars_group_stars{group="JavaScript"} 1`,
+		"stars_github_rate_limit_remaining 4999",
+		`stars_fetch_duration_seconds_bucket{le="+Inf"}`,
+	} {
+		require.Contains(response.Body.String(), line)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"html/template"
+	"io"
+)
+
+const (
+	DefaultBookmarksTitle = "GitHub Stars"
+)
+
+// bookmarksTemplate follows the Netscape Bookmark File format understood by
+// the Firefox and Chrome bookmark importers.
+var bookmarksTemplate = template.Must(template.New("bookmarks").Funcs(template.FuncMap(TemplateFuncs())).Parse(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3>{{.Title}}</H3>
+    <DL><p>
+{{- range .Rows}}
+        <DT><H3>{{.Language}}</H3>
+        <DL><p>
+{{- range .Repos}}
+            <DT><A HREF="{{.HTMLURL}}"{{if not .StarredAt.IsZero}} ADD_DATE="{{.StarredAt.Unix}}"{{end}}>{{.FullName}}</A>
+{{- if .Description}}
+            <DD>{{.Description}}
+{{- end}}
+{{- end}}
+        </DL><p>
+{{- end}}
+    </DL><p>
+</DL><p>
+`))
+
+// ensure interface implement is correct
+var _ Renderer = (*BookmarksPrinter)(nil)
+
+type BookmarksPrinterOption func(*BookmarksPrinter)
+
+// WithBookmarksTitle names the top level folder holding one folder per group
+func WithBookmarksTitle(title string) BookmarksPrinterOption {
+	return func(bookmarksPrinter *BookmarksPrinter) {
+		bookmarksPrinter.Title = title
+	}
+}
+
+func WithBookmarksOutputPath(outputPath string) BookmarksPrinterOption {
+	return func(bookmarksPrinter *BookmarksPrinter) {
+		bookmarksPrinter.OutputPath = outputPath
+	}
+}
+
+type BookmarksPrinter struct {
+	Title      string
+	OutputPath string
+}
+
+func NewBookmarksPrinter(setters ...BookmarksPrinterOption) (*BookmarksPrinter, error) {
+	bookmarksPrinter := &BookmarksPrinter{
+		Title:      DefaultBookmarksTitle,
+		OutputPath: "",
+	}
+
+	for _, setter := range setters {
+		setter(bookmarksPrinter)
+	}
+
+	if bookmarksPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return bookmarksPrinter, nil
+}
+
+func (self *BookmarksPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *BookmarksPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *BookmarksPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	return bookmarksTemplate.Execute(wr, struct {
+		Title string
+		Rows  []MarkDownRow
+	}{
+		Title: self.Title,
+		Rows:  markDownRows,
+	})
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBookmarksPrinterWithEmptyOutputPath(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewBookmarksPrinter()
+	require.EqualError(err, ErrorOutputPath)
+	require.Nil(printer)
+}
+
+func TestBookmarksPrinterPrint(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewBookmarksPrinter(
+		WithBookmarksTitle("Stars & more"),
+		WithBookmarksOutputPath("bookmarks.html"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	requireGolden(t, "bookmarks.golden.html", output.String())
+}
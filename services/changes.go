@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+	"time"
+)
+
+const (
+	ChangesMarkdown = "markdown"
+	ChangesJSON     = "json"
+
+	ErrorChangesFormat = "Unknown changes format %q"
+
+	// changesPeriod is the period heading a changelog section
+	changesPeriod = "2006-01"
+)
+
+// changesTemplate renders the changelog section of the changes format
+var changesTemplate = template.Must(template.New("changes").Parse(`## {{.Summary}}
+{{if .Starred}}
+### Starred
+{{range .Starred}}
+#### {{.Group}}
+
+{{range .Repos}}- [{{.FullName}}]({{.URL}})
+{{end}}{{end}}{{end}}{{if .Unstarred}}
+### Unstarred
+{{range .Unstarred}}
+#### {{.Group}}
+
+{{range .Repos}}- [{{.FullName}}]({{.URL}})
+{{end}}{{end}}{{end}}`))
+
+// Changelog is what was starred and unstarred since a previous snapshot,
+// grouped the same way as the output. Templates get it from the changes
+// function, e.g.
+//
+//	{{with changes}}{{if .Changed}}{{.Summary}}{{end}}{{end}}
+type Changelog struct {
+	Period    string           `json:"period"`
+	Starred   []ChangelogGroup `json:"starred"`
+	Unstarred []ChangelogGroup `json:"unstarred"`
+}
+
+type ChangelogGroup struct {
+	Group string          `json:"group"`
+	Repos []ChangelogRepo `json:"repos"`
+}
+
+type ChangelogRepo struct {
+	FullName string `json:"full_name"`
+	URL      string `json:"url"`
+}
+
+// NewChangelog compares a previous full_name to group snapshot, from
+// ParseOutputSnapshot or HistoryRun.Snapshot, with the current rows. A nil
+// previous snapshot means there is nothing to compare with and gives an
+// empty changelog rather than every repository as starred.
+func NewChangelog(at time.Time, previous map[string]string, markDownRows []MarkDownRow) Changelog {
+	changelog := Changelog{
+		Period:    at.UTC().Format(changesPeriod),
+		Starred:   []ChangelogGroup{},
+		Unstarred: []ChangelogGroup{},
+	}
+	if previous == nil {
+		return changelog
+	}
+	diff := DiffSnapshots(previous, RowsSnapshot(markDownRows))
+	changelog.Starred = changelogGroups(diff.Starred, func(v StarChange) string { return v.To })
+	changelog.Unstarred = changelogGroups(diff.Unstarred, func(v StarChange) string { return v.From })
+	return changelog
+}
+
+func changelogGroups(changes []StarChange, group func(StarChange) string) []ChangelogGroup {
+	byGroup := make(map[string][]ChangelogRepo)
+	for _, v := range changes {
+		name := group(v)
+		if name == "" {
+			name = Others
+		}
+		byGroup[name] = append(byGroup[name], ChangelogRepo{
+			FullName: v.FullName,
+			URL:      "https://github.com/" + v.FullName,
+		})
+	}
+	groups := make([]ChangelogGroup, 0, len(byGroup))
+	for name, repos := range byGroup {
+		groups = append(groups, ChangelogGroup{Group: name, Repos: repos})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Group < groups[j].Group
+	})
+	return groups
+}
+
+// RowsSnapshot is the full_name to group snapshot of rows
+func RowsSnapshot(markDownRows []MarkDownRow) map[string]string {
+	snapshot := make(map[string]string)
+	for _, row := range markDownRows {
+		for _, v := range row.Repos {
+			snapshot[v.FullName] = row.Language
+		}
+	}
+	return snapshot
+}
+
+func (self Changelog) StarredCount() int {
+	return changelogCount(self.Starred)
+}
+
+func (self Changelog) UnstarredCount() int {
+	return changelogCount(self.Unstarred)
+}
+
+func changelogCount(groups []ChangelogGroup) int {
+	count := 0
+	for _, group := range groups {
+		count += len(group.Repos)
+	}
+	return count
+}
+
+func (self Changelog) Changed() bool {
+	return len(self.Starred)+len(self.Unstarred) > 0
+}
+
+// Summary is the changelog heading, e.g. "2026-10: +14 starred, -3 unstarred"
+func (self Changelog) Summary() string {
+	return fmt.Sprintf("%s: +%d starred, -%d unstarred", self.Period, self.StarredCount(), self.UnstarredCount())
+}
+
+// ensure interface implement is correct
+var _ Renderer = (*ChangesPrinter)(nil)
+
+type ChangesPrinterOption func(*ChangesPrinter)
+
+func WithChangesFormat(format string) ChangesPrinterOption {
+	return func(changesPrinter *ChangesPrinter) {
+		changesPrinter.Format = format
+	}
+}
+
+// WithChangesPrevious sets the snapshot the rows are compared with
+func WithChangesPrevious(previous map[string]string) ChangesPrinterOption {
+	return func(changesPrinter *ChangesPrinter) {
+		changesPrinter.Previous = previous
+	}
+}
+
+func WithChangesOutputPath(outputPath string) ChangesPrinterOption {
+	return func(changesPrinter *ChangesPrinter) {
+		changesPrinter.OutputPath = outputPath
+	}
+}
+
+// ChangesPrinter writes the changelog section alone, as markdown or JSON
+type ChangesPrinter struct {
+	Format     string
+	Previous   map[string]string
+	OutputPath string
+}
+
+func NewChangesPrinter(setters ...ChangesPrinterOption) (*ChangesPrinter, error) {
+	changesPrinter := &ChangesPrinter{
+		Format:     ChangesMarkdown,
+		Previous:   nil,
+		OutputPath: "",
+	}
+
+	for _, setter := range setters {
+		setter(changesPrinter)
+	}
+
+	if changesPrinter.Format != ChangesMarkdown && changesPrinter.Format != ChangesJSON {
+		return nil, fmt.Errorf(ErrorChangesFormat, changesPrinter.Format)
+	}
+
+	if changesPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return changesPrinter, nil
+}
+
+func (self *ChangesPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *ChangesPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *ChangesPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	changelog := NewChangelog(now(), self.Previous, markDownRows)
+	if self.Format == ChangesJSON {
+		encoder := json.NewEncoder(wr)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changelog)
+	}
+	return changesTemplate.Execute(wr, changelog)
+}
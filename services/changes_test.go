@@ -0,0 +1,155 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChangelog(t *testing.T) {
+	require := require.New(t)
+	at := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	previous := map[string]string{
+		"victorspringer/http-cache": "Go",
+		"a/gone":                    "Rust",
+		"a/lost":                    "",
+	}
+	changelog := NewChangelog(at, previous, testStarredRows())
+	require.Equal(Changelog{
+		Period: "2026-10",
+		Starred: []ChangelogGroup{
+			{Group: "JavaScript", Repos: []ChangelogRepo{{FullName: "stefanwuthrich/cached-google-places", URL: "https://github.com/stefanwuthrich/cached-google-places"}}},
+		},
+		Unstarred: []ChangelogGroup{
+			{Group: Others, Repos: []ChangelogRepo{{FullName: "a/lost", URL: "https://github.com/a/lost"}}},
+			{Group: "Rust", Repos: []ChangelogRepo{{FullName: "a/gone", URL: "https://github.com/a/gone"}}},
+		},
+	}, changelog)
+	require.True(changelog.Changed())
+	require.Equal("2026-10: +1 starred, -2 unstarred", changelog.Summary())
+}
+
+func TestNewChangelogWithoutPrevious(t *testing.T) {
+	require := require.New(t)
+	changelog := NewChangelog(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), nil, testStarredRows())
+	require.False(changelog.Changed())
+	require.Equal("2026-10: +0 starred, -0 unstarred", changelog.Summary())
+}
+
+func TestNewChangesPrinterWithUnknownFormat(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewChangesPrinter(WithChangesFormat("yaml"), WithChangesOutputPath("changes.md"))
+	require.EqualError(err, `Unknown changes format "yaml"`)
+	require.Nil(printer)
+}
+
+func TestChangesPrinterPrint(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	now = func() time.Time { return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) }
+
+	printer, err := NewChangesPrinter(
+		WithChangesPrevious(map[string]string{"victorspringer/http-cache": "Go", "a/gone": "Rust"}),
+		WithChangesOutputPath("changes.md"),
+	)
+	require.NoError(err)
+	var output strings.Builder
+	require.NoError(printer.Print(&output, testStarredRows()))
+	require.Equal(`## 2026-10: +1 starred, -1 unstarred
+
+### Starred
+
+#### JavaScript
+
+- [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places)
+
+### Unstarred
+
+#### Rust
+
+- [a/gone](https://github.com/a/gone)
+`, output.String())
+
+	printer.Format = ChangesJSON
+	output.Reset()
+	require.NoError(printer.Print(&output, testStarredRows()))
+	require.Contains(output.String(), `"period": "2026-10"`)
+	require.Contains(output.String(), `"full_name": "a/gone"`)
+}
+
+func TestTplPrinterChanges(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}{{with changes}}{{.StarredCount}} {{.UnstarredCount}}{{end}}{{end}}`)),
+		WithOutputPath("out.md"),
+		WithPreviousSnapshot(map[string]string{"a/gone": "Rust"}),
+	)
+	require.NoError(err)
+	output, err := printer.Render(testStarredRows())
+	require.NoError(err)
+	require.Equal("2 1", string(output))
+}
+
+func TestTplPrinterChangesWithSharedTemplate(t *testing.T) {
+	require := require.New(t)
+	tpl := template.Must(template.New("layout").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}{{with changes}}{{.StarredCount}} {{.UnstarredCount}}{{end}}{{end}}`))
+	gone, err := NewTplPrinter(
+		WithBaseTemplate(tpl, nil),
+		WithOutputPath("out.md"),
+		WithPreviousSnapshot(map[string]string{"a/gone": "Rust"}),
+	)
+	require.NoError(err)
+	same, err := NewTplPrinter(
+		WithBaseTemplate(tpl, nil),
+		WithOutputPath("out.md"),
+		WithPreviousSnapshot(map[string]string{
+			"victorspringer/http-cache":           "Go",
+			"stefanwuthrich/cached-google-places": "JavaScript",
+		}),
+	)
+	require.NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			output, err := gone.Render(testStarredRows())
+			require.NoError(err)
+			require.Equal("2 1", string(output))
+		}()
+		go func() {
+			defer wg.Done()
+			output, err := same.Render(testStarredRows())
+			require.NoError(err)
+			require.Equal("0 0", string(output))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTplPrinterChangesWithDefaultPack(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(ParseTemplatePack(testPackFS, "starred")),
+		WithOutputPath("out.md"),
+	)
+	require.NoError(err)
+	first, err := printer.Render(testStarredRows())
+	require.NoError(err)
+
+	// the links of the default layout itself are not taken for unstarred repositories
+	printer.Previous = ParseOutputSnapshot(first)
+	second, err := printer.Render(testStarredRows())
+	require.NoError(err)
+	require.Equal(string(first), string(second))
+	changelog := NewChangelog(now(), printer.Previous, testStarredRows())
+	require.Empty(changelog.Starred)
+	require.Empty(changelog.Unstarred)
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	ErrorChart = "Unknown chart %q"
+
+	// ChartsDir holds the charts next to the rendered output
+	ChartsDir          = "charts"
+	ChartLanguages     = "languages"
+	ChartStarsPerMonth = "stars-per-month"
+	ChartTopOwners     = "top-owners"
+
+	DefaultLanguageColor = "#cccccc"
+	// OtherLanguages merges the languages past chartSlices in the donut
+	OtherLanguages = "Other"
+	chartSlices    = 8
+	// chartMonths is how many months back the stars per month chart goes
+	chartMonths = 24
+	chartBar    = "#0969da"
+	chartFont   = `font-family="-apple-system,BlinkMacSystemFont,Segoe UI,Helvetica,Arial,sans-serif" font-size="12" fill="#57606a"`
+)
+
+// languageColors are the colors GitHub linguist gives languages
+var languageColors = map[string]string{
+	"Assembly":         "#6E4C13",
+	"C":                "#555555",
+	"C#":               "#178600",
+	"C++":              "#f34b7d",
+	"CSS":              "#563d7c",
+	"Clojure":          "#db5855",
+	"Dart":             "#00B4AB",
+	"Dockerfile":       "#384d54",
+	"Elixir":           "#6e4a7e",
+	"Erlang":           "#B83998",
+	"Go":               "#00ADD8",
+	"HCL":              "#844FBA",
+	"HTML":             "#e34c26",
+	"Haskell":          "#5e5086",
+	"Java":             "#b07219",
+	"JavaScript":       "#f1e05a",
+	"Julia":            "#a270ba",
+	"Jupyter Notebook": "#DA5B0B",
+	"Kotlin":           "#A97BFF",
+	"Lua":              "#000080",
+	"Makefile":         "#427819",
+	"Nix":              "#7e7eff",
+	"OCaml":            "#ef7a08",
+	"Objective-C":      "#438eff",
+	"PHP":              "#4F5D95",
+	"Perl":             "#0298c3",
+	"PowerShell":       "#012456",
+	"Python":           "#3572A5",
+	"R":                "#198CE7",
+	"Ruby":             "#701516",
+	"Rust":             "#dea584",
+	"Scala":            "#c22d40",
+	"Shell":            "#89e051",
+	"Solidity":         "#AA6746",
+	"Svelte":           "#ff3e00",
+	"Swift":            "#F05138",
+	"TeX":              "#3D6117",
+	"TypeScript":       "#3178c6",
+	"Vim script":       "#199f4b",
+	"Vue":              "#41b883",
+	"Zig":              "#ec915c",
+}
+
+// charts renders the charts by name, every chart is a standalone SVG
+var charts = map[string]func(io.Writer, Stats){
+	ChartLanguages:     languagesChart,
+	ChartStarsPerMonth: starsPerMonthChart,
+	ChartTopOwners:     topOwnersChart,
+}
+
+func LanguageColor(language string) string {
+	if color, ok := languageColors[language]; ok {
+		return color
+	}
+	return DefaultLanguageColor
+}
+
+// ChartNames lists the charts in alphabetical order
+func ChartNames() []string {
+	names := make([]string, 0, len(charts))
+	for name := range charts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChartPath is the path of a chart relative to the output, the split mode
+// rebinds it relative to the group files. For templates:
+//
+//	![Languages]({{chart "languages"}})
+func ChartPath(name string) (string, error) {
+	if _, ok := charts[name]; !ok {
+		return "", fmt.Errorf(ErrorChart, name)
+	}
+	return path.Join(ChartsDir, name+".svg"), nil
+}
+
+func RenderChart(name string, stats Stats) ([]byte, error) {
+	chart, ok := charts[name]
+	if !ok {
+		return nil, fmt.Errorf(ErrorChart, name)
+	}
+	var output bytes.Buffer
+	chart(&output, stats)
+	return output.Bytes(), nil
+}
+
+// WriteCharts writes every chart into <dir>/charts/<name>.svg
+func WriteCharts(dir string, stats Stats) error {
+	if err := os.MkdirAll(filepath.Join(dir, ChartsDir), 0755); err != nil {
+		return err
+	}
+	for _, name := range ChartNames() {
+		chart := charts[name]
+		chartPath, _ := ChartPath(name)
+		err := writeOutput(filepath.Join(dir, filepath.FromSlash(chartPath)), func(wr io.Writer) error {
+			chart(wr, stats)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func svgOpen(wr io.Writer, width int, height int, title string) {
+	fmt.Fprintf(wr, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`+"\n", width, height, width, height)
+	fmt.Fprintf(wr, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(wr, `<text x="10" y="20" %s font-weight="600">%s</text>`+"\n", chartFont, html.EscapeString(title))
+}
+
+func svgClose(wr io.Writer) {
+	fmt.Fprint(wr, "</svg>\n")
+}
+
+// languagesChart draws the language distribution as a donut, languages past
+// the first chartSlices-1 are merged
+func languagesChart(wr io.Writer, stats Stats) {
+	slices := stats.Languages
+	if len(slices) > chartSlices {
+		other := Count{Name: OtherLanguages}
+		for _, count := range slices[chartSlices-1:] {
+			other.Count += count.Count
+		}
+		slices = append(append([]Count(nil), slices[:chartSlices-1]...), other)
+	}
+
+	const cx, cy, radius, width = 110.0, 125.0, 72.5, 35.0
+	circumference := 2 * math.Pi * radius
+	svgOpen(wr, 400, 240, "Languages")
+	offset := 0.0
+	for i, slice := range slices {
+		fraction := 0.0
+		if stats.Total > 0 {
+			fraction = float64(slice.Count) / float64(stats.Total)
+		}
+		length := fraction * circumference
+		// the dash pattern repeats every circumference, shifting it back by
+		// offset starts the slice where the previous one ended
+		fmt.Fprintf(wr,
+			`<circle cx="%.0f" cy="%.0f" r="%.1f" fill="none" stroke="%s" stroke-width="%.0f" stroke-dasharray="%.2f %.2f" stroke-dashoffset="%.2f" transform="rotate(-90 %.0f %.0f)"/>`+"\n",
+			cx, cy, radius, LanguageColor(slice.Name), width, length, circumference-length, circumference-offset, cx, cy,
+		)
+		offset += length
+
+		y := 50 + i*22
+		fmt.Fprintf(wr, `<rect x="230" y="%d" width="12" height="12" rx="2" fill="%s"/>`+"\n", y, LanguageColor(slice.Name))
+		fmt.Fprintf(wr, `<text x="250" y="%d" %s>%s %.1f%%</text>`+"\n", y+10, chartFont, html.EscapeString(slice.Name), fraction*100)
+	}
+	svgClose(wr)
+}
+
+// starsPerMonthChart draws one bar per month over the last chartMonths
+// months with a star, months without any star included
+func starsPerMonthChart(wr io.Writer, stats Stats) {
+	perMonth := make(map[string]int, len(stats.Growth))
+	previous := 0
+	for _, growth := range stats.Growth {
+		perMonth[growth.Name] = growth.Count - previous
+		previous = growth.Count
+	}
+	var months []Count
+	if len(stats.Growth) > 0 {
+		first, _ := time.Parse(growthLayout, stats.Growth[0].Name)
+		last, _ := time.Parse(growthLayout, stats.Growth[len(stats.Growth)-1].Name)
+		if start := last.AddDate(0, 1-chartMonths, 0); first.Before(start) {
+			first = start
+		}
+		for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+			name := month.Format(growthLayout)
+			months = append(months, Count{Name: name, Count: perMonth[name]})
+		}
+	}
+
+	const left, top, plotWidth, plotHeight = 20.0, 40.0, 560.0, 150.0
+	max := 0
+	for _, month := range months {
+		if month.Count > max {
+			max = month.Count
+		}
+	}
+	svgOpen(wr, 600, 240, "Stars per month")
+	fmt.Fprintf(wr, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#d0d7de"/>`+"\n", left, top+plotHeight, left+plotWidth, top+plotHeight)
+	for i, month := range months {
+		step := plotWidth / float64(len(months))
+		x := left + float64(i)*step
+		height := 0.0
+		if max > 0 {
+			height = float64(month.Count) / float64(max) * plotHeight
+		}
+		fmt.Fprintf(wr, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"><title>%s: %d</title></rect>`+"\n",
+			x+step*0.1, top+plotHeight-height, step*0.8, height, chartBar, month.Name, month.Count)
+		if i%3 == 0 || i == len(months)-1 {
+			fmt.Fprintf(wr, `<text x="%.2f" y="%.0f" %s text-anchor="middle">%s</text>`+"\n", x+step/2, top+plotHeight+18, chartFont, month.Name)
+		}
+	}
+	svgClose(wr)
+}
+
+// topOwnersChart draws one horizontal bar per top owner
+func topOwnersChart(wr io.Writer, stats Stats) {
+	const left, top, barWidth, row = 170.0, 40, 360.0, 24
+	max := 0
+	for _, owner := range stats.TopOwners {
+		if owner.Count > max {
+			max = owner.Count
+		}
+	}
+	svgOpen(wr, 600, top+row*len(stats.TopOwners)+10, "Top owners")
+	for i, owner := range stats.TopOwners {
+		y := top + i*row
+		width := 0.0
+		if max > 0 {
+			width = float64(owner.Count) / float64(max) * barWidth
+		}
+		fmt.Fprintf(wr, `<text x="10" y="%d" %s>%s</text>`+"\n", y+15, chartFont, html.EscapeString(Truncate(24, owner.Name)))
+		fmt.Fprintf(wr, `<rect x="%.0f" y="%d" width="%.2f" height="18" rx="2" fill="%s"/>`+"\n", left, y+2, width, chartBar)
+		fmt.Fprintf(wr, `<text x="%.2f" y="%d" %s>%d</text>`+"\n", left+width+6, y+15, chartFont, owner.Count)
+	}
+	svgClose(wr)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguageColor(t *testing.T) {
+	require := require.New(t)
+	require.Equal("#00ADD8", LanguageColor("Go"))
+	require.Equal(DefaultLanguageColor, LanguageColor(Others))
+}
+
+func TestChartPath(t *testing.T) {
+	require := require.New(t)
+	chartPath, err := ChartPath(ChartLanguages)
+	require.NoError(err)
+	require.Equal("charts/languages.svg", chartPath)
+
+	_, err = ChartPath("pie")
+	require.EqualError(err, `Unknown chart "pie"`)
+}
+
+func TestRenderChart(t *testing.T) {
+	stats := ComputeStats(Repos(testStarredRows()), DefaultStatsTop, DefaultStaleAfter)
+	for _, name := range ChartNames() {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			chart, err := RenderChart(name, stats)
+			require.NoError(err)
+			requireGolden(t, name+".golden.svg", string(chart))
+		})
+	}
+}
+
+func TestRenderChartWithManyLanguages(t *testing.T) {
+	require := require.New(t)
+	var repos UserStarredRepositories
+	for i := 0; i < 10; i++ {
+		repos = append(repos, Repository{Language: fmt.Sprintf("Lang%d", i)})
+	}
+	chart, err := RenderChart(ChartLanguages, ComputeStats(repos, DefaultStatsTop, DefaultStaleAfter))
+	require.NoError(err)
+	require.Equal(chartSlices, strings.Count(string(chart), "<circle"))
+	require.Contains(string(chart), ">Other 30.0%<")
+}
+
+func TestRenderChartWithoutRepositories(t *testing.T) {
+	require := require.New(t)
+	stats := ComputeStats(nil, DefaultStatsTop, DefaultStaleAfter)
+	for _, name := range ChartNames() {
+		chart, err := RenderChart(name, stats)
+		require.NoError(err)
+		require.True(strings.HasSuffix(string(chart), "</svg>\n"))
+	}
+}
+
+func TestPrintSliceWithCharts(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "charts")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}![Languages]({{chart "languages"}}){{end}}`)),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithCharts(true),
+	)
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	output, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(err)
+	require.Equal("![Languages](charts/languages.svg)", string(output))
+	for _, name := range ChartNames() {
+		_, err := os.Stat(filepath.Join(dir, ChartsDir, name+".svg"))
+		require.NoError(err)
+	}
+}
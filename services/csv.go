@@ -0,0 +1,166 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrorCSVColumns = "Missing CSV columns"
+	ErrorCSVColumn  = "Unknown CSV column %q"
+
+	// CSVGroupColumn and CSVCountColumn lead every row when printing one row per group
+	CSVGroupColumn = "group"
+	CSVCountColumn = "count"
+	// CSVListSeparator joins the repository values of a group into one cell
+	CSVListSeparator = "\n"
+)
+
+// CSVColumns lists the columns supported by CSVPrinter, in their default order
+var CSVColumns = []string{
+	"full_name",
+	"url",
+	"language",
+	"stars",
+	"forks",
+	"license",
+	"starred_at",
+	"pushed_at",
+	"archived",
+	"topics",
+}
+
+// ensure interface implement is correct
+var _ Renderer = (*CSVPrinter)(nil)
+
+type CSVPrinterOption func(*CSVPrinter)
+
+func WithCSVColumns(columns []string) CSVPrinterOption {
+	return func(csvPrinter *CSVPrinter) {
+		csvPrinter.Columns = columns
+	}
+}
+
+// WithCSVComma sets the field delimiter, e.g. '\t' for TSV
+func WithCSVComma(comma rune) CSVPrinterOption {
+	return func(csvPrinter *CSVPrinter) {
+		csvPrinter.Comma = comma
+	}
+}
+
+// WithCSVPerGroup prints one row per group instead of one row per repository
+func WithCSVPerGroup(perGroup bool) CSVPrinterOption {
+	return func(csvPrinter *CSVPrinter) {
+		csvPrinter.PerGroup = perGroup
+	}
+}
+
+func WithCSVOutputPath(outputPath string) CSVPrinterOption {
+	return func(csvPrinter *CSVPrinter) {
+		csvPrinter.OutputPath = outputPath
+	}
+}
+
+type CSVPrinter struct {
+	Columns    []string
+	Comma      rune
+	PerGroup   bool
+	OutputPath string
+}
+
+func NewCSVPrinter(setters ...CSVPrinterOption) (*CSVPrinter, error) {
+	csvPrinter := &CSVPrinter{
+		Columns:    CSVColumns,
+		Comma:      ',',
+		PerGroup:   false,
+		OutputPath: "",
+	}
+
+	for _, setter := range setters {
+		setter(csvPrinter)
+	}
+
+	if len(csvPrinter.Columns) == 0 {
+		return nil, errors.New(ErrorCSVColumns)
+	}
+
+	for _, column := range csvPrinter.Columns {
+		if _, ok := repoFields[column]; !ok {
+			return nil, fmt.Errorf(ErrorCSVColumn, column)
+		}
+	}
+
+	if csvPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return csvPrinter, nil
+}
+
+func (self *CSVPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *CSVPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+// Print writes the header row followed by one record per repository, or
+// one record per group when PerGroup is set.
+func (self *CSVPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	w := csv.NewWriter(wr)
+	w.Comma = self.Comma
+
+	header := self.Columns
+	if self.PerGroup {
+		header = append([]string{CSVGroupColumn, CSVCountColumn}, self.Columns...)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range markDownRows {
+		if self.PerGroup {
+			if err := w.Write(self.groupRecord(row)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, v := range row.Repos {
+			if err := w.Write(self.repoRecord(v)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (self *CSVPrinter) repoRecord(v Repository) []string {
+	record := make([]string, 0, len(self.Columns))
+	for _, column := range self.Columns {
+		record = append(record, repoFields[column](v))
+	}
+	return record
+}
+
+func (self *CSVPrinter) groupRecord(row MarkDownRow) []string {
+	record := []string{row.Language, strconv.Itoa(len(row.Repos))}
+	for _, column := range self.Columns {
+		values := make([]string, 0, len(row.Repos))
+		for _, v := range row.Repos {
+			values = append(values, repoFields[column](v))
+		}
+		record = append(record, strings.Join(values, CSVListSeparator))
+	}
+	return record
+}
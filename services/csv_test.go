@@ -0,0 +1,106 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCSVPrinterWithEmptyOutputPath(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewCSVPrinter()
+	require.EqualError(err, ErrorOutputPath)
+	require.Nil(printer)
+}
+
+func TestNewCSVPrinterWithUnknownColumn(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewCSVPrinter(
+		WithCSVColumns([]string{"full_name", "owner"}),
+		WithCSVOutputPath("out.csv"),
+	)
+	require.EqualError(err, `Unknown CSV column "owner"`)
+	require.Nil(printer)
+}
+
+func TestNewCSVPrinterWithEmptyColumns(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewCSVPrinter(
+		WithCSVColumns([]string{}),
+		WithCSVOutputPath("out.csv"),
+	)
+	require.EqualError(err, ErrorCSVColumns)
+	require.Nil(printer)
+}
+
+func TestCSVPrinterPrint(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewCSVPrinter(WithCSVOutputPath("out.csv"))
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	require.Equal(`full_name,url,language,stars,forks,license,starred_at,pushed_at,archived,topics
+victorspringer/http-cache,https://github.com/victorspringer/http-cache,Go,183,20,MIT,2021-03-18T09:30:00Z,2021-03-02T10:00:00Z,false,cache golang
+stefanwuthrich/cached-google-places,https://github.com/stefanwuthrich/cached-google-places,JavaScript,1,0,,2021-03-19T12:00:00Z,2021-02-01T08:00:00Z,true,
+`, output.String())
+}
+
+func TestCSVPrinterPrintQuoting(t *testing.T) {
+	require := require.New(t)
+	rows := testStarredRows()
+	rows[0].Repos[0].FullName = `a,"b"`
+	printer, err := NewCSVPrinter(
+		WithCSVColumns([]string{"full_name"}),
+		WithCSVOutputPath("out.csv"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, rows[:1])
+	require.NoError(err)
+	require.Equal("full_name\n\"a,\"\"b\"\"\"\n", output.String())
+}
+
+func TestCSVPrinterPrintPerGroupTSV(t *testing.T) {
+	require := require.New(t)
+	rows := testStarredRows()
+	rows[0].Repos = append(rows[0].Repos, rows[1].Repos[0])
+	printer, err := NewCSVPrinter(
+		WithCSVColumns([]string{"full_name", "stars"}),
+		WithCSVComma('\t'),
+		WithCSVPerGroup(true),
+		WithCSVOutputPath("out.tsv"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, rows)
+	require.NoError(err)
+	require.Equal("group\tcount\tfull_name\tstars\n"+
+		"Go\t2\t\"victorspringer/http-cache\nstefanwuthrich/cached-google-places\"\t\"183\n1\"\n"+
+		"JavaScript\t1\tstefanwuthrich/cached-google-places\t1\n", output.String())
+}
+
+func TestCSVPrinterPrintSlice(t *testing.T) {
+	require := require.New(t)
+	tmpfile, err := ioutil.TempFile("", "out.*.csv")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+
+	printer, err := NewCSVPrinter(
+		WithCSVColumns([]string{"full_name", "language"}),
+		WithCSVOutputPath(tmpfile.Name()),
+	)
+	require.NoError(err)
+	err = printer.PrintSlice(testStarredRows())
+	require.NoError(err)
+
+	actual, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(err)
+	require.Equal("full_name,language\nvictorspringer/http-cache,Go\nstefanwuthrich/cached-google-places,JavaScript\n", string(actual))
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines around each hunk
+const DiffContext = 3
+
+type diffOp struct {
+	Kind byte
+	Line string
+}
+
+// UnifiedDiff returns a unified diff turning a into b, or "" when they are
+// equal.
+func UnifiedDiff(fromName string, toName string, a []byte, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].Kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk while changes are close enough to share context
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].Kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*DiffContext {
+				break
+			}
+		}
+		from := start - DiffContext
+		if from < 0 {
+			from = 0
+		}
+		to := end + DiffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+		writeHunk(&output, ops, from, to)
+		start = to
+	}
+	return output.String()
+}
+
+func writeHunk(output *strings.Builder, ops []diffOp, from int, to int) {
+	aLine, bLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.Kind != '+' {
+			aLine++
+		}
+		if op.Kind != '-' {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.Kind != '+' {
+			aCount++
+		}
+		if op.Kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(output, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	for _, op := range ops[from:to] {
+		output.WriteByte(op.Kind)
+		output.WriteString(op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			output.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		// an empty range points at the line before the change
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines is Myers' O(ND) diff returning the edit script from a to b
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	var found bool
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY && x > 0 && y > 0 {
+			ops = append(ops, diffOp{Kind: ' ', Line: a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{Kind: '+', Line: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{Kind: '-', Line: a[x-1]})
+			x--
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// StarChange is one repository of a SemanticDiff, From and To are its
+// groups before and after.
+type StarChange struct {
+	FullName string
+	From     string
+	To       string
+}
+
+type SemanticDiff struct {
+	Starred   []StarChange
+	Unstarred []StarChange
+	Moved     []StarChange
+}
+
+func (self SemanticDiff) Changed() bool {
+	return len(self.Starred)+len(self.Unstarred)+len(self.Moved) > 0
+}
+
+func (self SemanticDiff) String() string {
+	var output strings.Builder
+	for _, v := range self.Starred {
+		fmt.Fprintf(&output, "+ %s%s\n", v.FullName, groupSuffix(v.To))
+	}
+	for _, v := range self.Unstarred {
+		fmt.Fprintf(&output, "- %s%s\n", v.FullName, groupSuffix(v.From))
+	}
+	for _, v := range self.Moved {
+		fmt.Fprintf(&output, "~ %s: %s -> %s\n", v.FullName, v.From, v.To)
+	}
+	fmt.Fprintf(&output, "%d starred, %d unstarred, %d moved\n", len(self.Starred), len(self.Unstarred), len(self.Moved))
+	return output.String()
+}
+
+func groupSuffix(group string) string {
+	if group == "" {
+		return ""
+	}
+	return " (" + group + ")"
+}
+
+// DiffSnapshots compares two full_name to group snapshots. An empty group
+// means unknown and never counts as a move.
+func DiffSnapshots(before map[string]string, after map[string]string) SemanticDiff {
+	var diff SemanticDiff
+	for fullName, to := range after {
+		from, ok := before[fullName]
+		if !ok {
+			diff.Starred = append(diff.Starred, StarChange{FullName: fullName, To: to})
+		} else if from != to && from != "" && to != "" {
+			diff.Moved = append(diff.Moved, StarChange{FullName: fullName, From: from, To: to})
+		}
+	}
+	for fullName, from := range before {
+		if _, ok := after[fullName]; !ok {
+			diff.Unstarred = append(diff.Unstarred, StarChange{FullName: fullName, From: from})
+		}
+	}
+	for _, changes := range [][]StarChange{diff.Starred, diff.Unstarred, diff.Moved} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].FullName < changes[j].FullName
+		})
+	}
+	return diff
+}
+
+var (
+	// outputRepoLink only matches links to a repository itself, so the
+	// badges, docs and settings links of a layout are not taken for stars
+	outputRepoLink = regexp.MustCompile(`(?i)(?:\]\(|href=["'])https://github\.com/([\w.-]+/[\w.-]+)["')]`)
+	// outputRepoRow matches the table rows, list items and html rows the
+	// repositories are rendered in
+	outputRepoRow = regexp.MustCompile(`(?i)^\s*(?:[-*+]|\d+\.)\s|\||<(?:li|td)[\s>]`)
+	outputHeading = regexp.MustCompile(`^\s*#{1,6}\s+(.+?)\s*#*\s*$|<[hH][1-6][^>]*>([^<]+)</[hH][1-6]>`)
+	markdownLink  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+)
+
+// ParseOutputSnapshot recovers the repositories of a rendered output as a
+// full_name to group snapshot. It is best effort: only the links to a
+// repository from a table row or a list item are read, and a repository's
+// group is the first cell of its markdown table row, or else the closest
+// heading above it.
+func ParseOutputSnapshot(output []byte) map[string]string {
+	snapshot := make(map[string]string)
+	heading := ""
+	for _, line := range splitLines(output) {
+		if match := outputHeading.FindStringSubmatch(line); match != nil {
+			heading = cleanGroup(match[1] + match[2])
+		}
+		if !outputRepoRow.MatchString(line) {
+			continue
+		}
+		group := heading
+		if cells := strings.Split(line, "|"); len(cells) > 1 {
+			group = cleanGroup(cells[0])
+		}
+		for _, match := range outputRepoLink.FindAllStringSubmatch(line, -1) {
+			fullName := strings.TrimSuffix(strings.TrimRight(match[1], "."), ".git")
+			if _, ok := snapshot[fullName]; !ok {
+				snapshot[fullName] = group
+			}
+		}
+	}
+	return snapshot
+}
+
+func cleanGroup(s string) string {
+	return strings.TrimSpace(markdownLink.ReplaceAllString(s, "$1"))
+}
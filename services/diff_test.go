@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiffEqual(t *testing.T) {
+	require := require.New(t)
+	require.Equal("", UnifiedDiff("a", "b", []byte("x\ny\n"), []byte("x\ny\n")))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	require := require.New(t)
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n14\n15\n16\n"
+	expected := `--- a/out.md
++++ b/out.md
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,6 +10,6 @@
+ 10
+ 11
+ 12
+-13
+ 14
+ 15
++16
+`
+	require.Equal(expected, UnifiedDiff("a/out.md", "b/out.md", []byte(a), []byte(b)))
+}
+
+func TestUnifiedDiffWithoutTrailingNewline(t *testing.T) {
+	require := require.New(t)
+	expected := `--- a
++++ b
+@@ -1 +1,2 @@
+-x
+\ No newline at end of file
++x
++y
+`
+	require.Equal(expected, UnifiedDiff("a", "b", []byte("x"), []byte("x\ny\n")))
+	require.Equal("--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n", UnifiedDiff("a", "b", nil, []byte("x\n")))
+}
+
+func TestParseOutputSnapshot(t *testing.T) {
+	require := require.New(t)
+	output := []byte(`![test](https://github.com/AlphaWong/Stars/workflows/test/badge.svg)
+see https://github.com/settings/tokens
+- [docs](https://github.com/AlphaWong/Stars/blob/master/README.md)
+# Result
+Language|⭐️|Repos
+---|---|---
+Go|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]
+## [Rust](#rust)
+- [a/b](https://github.com/a/b.git)
+<H3>Shell</H3>
+<TD><A HREF="https://github.com/c/d">c/d</A></TD>
+`)
+	require.Equal(map[string]string{
+		"victorspringer/http-cache": "Go",
+		"a/b":                       "Rust",
+		"c/d":                       "Shell",
+	}, ParseOutputSnapshot(output))
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	require := require.New(t)
+	diff := DiffSnapshots(
+		map[string]string{"a/kept": "Go", "a/moved": "Go", "a/gone": "C", "a/unknown": ""},
+		map[string]string{"a/kept": "Go", "a/moved": "Rust", "a/new": "Go", "a/unknown": "Go"},
+	)
+	require.True(diff.Changed())
+	require.Equal([]StarChange{{FullName: "a/new", To: "Go"}}, diff.Starred)
+	require.Equal([]StarChange{{FullName: "a/gone", From: "C"}}, diff.Unstarred)
+	require.Equal([]StarChange{{FullName: "a/moved", From: "Go", To: "Rust"}}, diff.Moved)
+	require.Equal("+ a/new (Go)\n- a/gone (C)\n~ a/moved: Go -> Rust\n1 starred, 1 unstarred, 1 moved\n", diff.String())
+	require.False(DiffSnapshots(map[string]string{"a": "Go"}, map[string]string{"a": "Go"}).Changed())
+}
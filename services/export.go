@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ExportVersion is bumped whenever the export file changes in a way
+	// older versions of import cannot read
+	ExportVersion = 1
+
+	ErrorExportVersion = "Unsupported export version %d, want %d"
+
+	ImportStarred = "starred"
+	ImportMissing = "missing"
+	ImportFailed  = "failed"
+)
+
+// StarsExport is the portable export file
+type StarsExport struct {
+	Version    int            `json:"version"`
+	UserName   string         `json:"user"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Stars      []ExportedStar `json:"stars"`
+}
+
+// ExportedStar identifies a starred repository, import finds the ones
+// renamed since by their node_id. Lists are the names of the star lists the
+// repository is in, import puts it back into them.
+type ExportedStar struct {
+	FullName  string    `json:"full_name"`
+	NodeID    string    `json:"node_id"`
+	StarredAt time.Time `json:"starred_at"`
+	Lists     []string  `json:"lists"`
+}
+
+// ImportSummary counts what import did, Missing lists the repositories
+// which no longer exist and Renamed maps the ones starred under a new name
+// to it
+type ImportSummary struct {
+	Starred int
+	Skipped int
+	Failed  []StarResult
+	Missing []string
+	Renamed map[string]string
+}
+
+// NewStarsExport exports repositories oldest star first, the order import
+// stars them in. starLists are the star list names by owner/repo, see
+// GitHubStarrer.StarLists.
+func NewStarsExport(userName string, userStarredRepositories UserStarredRepositories, starLists map[string][]string) StarsExport {
+	stars := make([]ExportedStar, 0, len(userStarredRepositories))
+	for _, v := range userStarredRepositories {
+		lists := starLists[v.FullName]
+		if lists == nil {
+			lists = []string{}
+		}
+		stars = append(stars, ExportedStar{
+			FullName:  v.FullName,
+			NodeID:    v.NodeID,
+			StarredAt: v.StarredAt,
+			Lists:     lists,
+		})
+	}
+	sort.SliceStable(stars, func(i, j int) bool {
+		if !stars[i].StarredAt.Equal(stars[j].StarredAt) {
+			return stars[i].StarredAt.Before(stars[j].StarredAt)
+		}
+		return strings.ToLower(stars[i].FullName) < strings.ToLower(stars[j].FullName)
+	})
+	return StarsExport{
+		Version:    ExportVersion,
+		UserName:   userName,
+		ExportedAt: now().UTC(),
+		Stars:      stars,
+	}
+}
+
+func WriteStarsExport(outputPath string, export StarsExport) error {
+	return writeOutput(outputPath, func(wr io.Writer) error {
+		encoder := json.NewEncoder(wr)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	})
+}
+
+func ReadStarsExport(r io.Reader) (StarsExport, error) {
+	var export StarsExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return export, err
+	}
+	if export.Version != ExportVersion {
+		return export, fmt.Errorf(ErrorExportVersion, export.Version, ExportVersion)
+	}
+	return export, nil
+}
+
+// ImportState records, one "<result>\t<full_name>" line per repository, what
+// an import already did so that an interrupted import resumes where it
+// stopped instead of starting over.
+type ImportState struct {
+	results map[string]string
+	file    *os.File
+}
+
+// OpenImportState reads the state left by a previous import and appends to it
+func OpenImportState(statePath string) (*ImportState, error) {
+	file, err := os.OpenFile(statePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	state := &ImportState{results: make(map[string]string), file: file}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) == 2 {
+			state.results[parts[1]] = parts[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return state, nil
+}
+
+// Result is what a previous import did with fullName, "" when nothing
+func (self *ImportState) Result(fullName string) string {
+	return self.results[fullName]
+}
+
+// Record saves result for fullName, synced so it survives a crash
+func (self *ImportState) Record(fullName string, result string) error {
+	self.results[fullName] = result
+	if _, err := fmt.Fprintf(self.file, "%s\t%s\n", result, fullName); err != nil {
+		return err
+	}
+	return self.file.Sync()
+}
+
+func (self *ImportState) Close() error {
+	return self.file.Close()
+}
+
+// ImportStars stars every repository of export which state has not starred
+// or found missing yet and puts it back into its star lists. Failed ones,
+// including the ones whose lists could not be set, are recorded too but
+// retried on the next import.
+func ImportStars(starrer Starrer, export StarsExport, state *ImportState) (ImportSummary, error) {
+	var summary ImportSummary
+	for _, star := range export.Stars {
+		switch state.Result(star.FullName) {
+		case ImportStarred:
+			summary.Skipped++
+			continue
+		case ImportMissing:
+			summary.Missing = append(summary.Missing, star.FullName)
+			continue
+		}
+
+		result := ImportStarred
+		fullName, err := importStar(starrer, star)
+		if err == nil {
+			err = importLists(starrer, star)
+		}
+		switch {
+		case err == nil:
+			summary.Starred++
+			if fullName != star.FullName {
+				if summary.Renamed == nil {
+					summary.Renamed = make(map[string]string)
+				}
+				summary.Renamed[star.FullName] = fullName
+			}
+		case errors.Is(err, ErrNotFound):
+			result = ImportMissing
+			summary.Missing = append(summary.Missing, star.FullName)
+		default:
+			result = ImportFailed
+			summary.Failed = append(summary.Failed, StarResult{
+				FullName: star.FullName,
+				Action:   ActionStar,
+				Result:   StarResultFailed,
+				Error:    err.Error(),
+			})
+		}
+		if err := state.Record(star.FullName, result); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// importStar stars the repository of star and returns the name it starred.
+// A repository renamed since the export is found by its node_id when the
+// starrer is a NodeResolver.
+func importStar(starrer Starrer, star ExportedStar) (string, error) {
+	err := starrer.Star(star.FullName)
+	resolver, ok := starrer.(NodeResolver)
+	if !errors.Is(err, ErrNotFound) || !ok || star.NodeID == "" {
+		return star.FullName, err
+	}
+	fullName, resolveErr := resolver.ResolveNode(star.NodeID)
+	switch {
+	case errors.Is(resolveErr, ErrNotFound):
+		return star.FullName, err
+	case resolveErr != nil:
+		return star.FullName, resolveErr
+	case strings.EqualFold(fullName, star.FullName):
+		return star.FullName, err
+	}
+	return fullName, starrer.Star(fullName)
+}
+
+// importLists puts the repository of star back into its star lists when
+// the starrer is a ListStarrer
+func importLists(starrer Starrer, star ExportedStar) error {
+	lister, ok := starrer.(ListStarrer)
+	if !ok || len(star.Lists) == 0 || star.NodeID == "" {
+		return nil
+	}
+	return lister.SetStarLists(star.NodeID, star.Lists)
+}
+
+// WriteImportSummary prints the counts followed by the missing and failed
+// repositories
+func WriteImportSummary(wr io.Writer, summary ImportSummary) error {
+	_, err := fmt.Fprintf(wr, "%d starred, %d already starred, %d missing, %d failed\n",
+		summary.Starred, summary.Skipped, len(summary.Missing), len(summary.Failed))
+	if err != nil {
+		return err
+	}
+	renamed := make([]string, 0, len(summary.Renamed))
+	for fullName := range summary.Renamed {
+		renamed = append(renamed, fullName)
+	}
+	sort.Strings(renamed)
+	for _, fullName := range renamed {
+		if _, err := fmt.Fprintf(wr, "renamed %s to %s\n", fullName, summary.Renamed[fullName]); err != nil {
+			return err
+		}
+	}
+	for _, fullName := range summary.Missing {
+		if _, err := fmt.Fprintf(wr, "missing %s\n", fullName); err != nil {
+			return err
+		}
+	}
+	for _, result := range summary.Failed {
+		if _, err := fmt.Fprintf(wr, "failed %s: %s\n", result.FullName, result.Error); err != nil {
+			return err
+		}
+	}
+	return nil
+}
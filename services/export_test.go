@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStarrer answers from errs by full name and records the calls
+type fakeStarrer struct {
+	errs    map[string]error
+	starred []string
+}
+
+func (self *fakeStarrer) Star(fullName string) error {
+	self.starred = append(self.starred, fullName)
+	return self.errs[fullName]
+}
+
+func (self *fakeStarrer) Unstar(fullName string) error {
+	return self.errs[fullName]
+}
+
+// fakeResolver also resolves node_ids to the current name of repositories
+type fakeResolver struct {
+	fakeStarrer
+	names map[string]string
+}
+
+func (self *fakeResolver) ResolveNode(nodeID string) (string, error) {
+	if fullName, ok := self.names[nodeID]; ok {
+		return fullName, nil
+	}
+	return "", ErrNotFound
+}
+
+// fakeListStarrer also records the star lists set by node_id
+type fakeListStarrer struct {
+	fakeStarrer
+	listErrs map[string]error
+	lists    map[string][]string
+}
+
+func (self *fakeListStarrer) SetStarLists(nodeID string, lists []string) error {
+	if err := self.listErrs[nodeID]; err != nil {
+		return err
+	}
+	self.lists[nodeID] = lists
+	return nil
+}
+
+func TestNewStarsExport(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	now = func() time.Time { return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC) }
+
+	repos := Repos(testStarredRows())
+	export := NewStarsExport("alphawong", UserStarredRepositories{repos[1], repos[0]}, map[string][]string{
+		"victorspringer/http-cache": {"go", "web"},
+	})
+	require.Equal(StarsExport{
+		Version:    ExportVersion,
+		UserName:   "alphawong",
+		ExportedAt: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		Stars: []ExportedStar{
+			{FullName: "victorspringer/http-cache", NodeID: "MDEwOlJlcG9zaXRvcnk3Mzg5MzQ3Mg==", StarredAt: time.Date(2021, 3, 18, 9, 30, 0, 0, time.UTC), Lists: []string{"go", "web"}},
+			{FullName: "stefanwuthrich/cached-google-places", NodeID: "MDEwOlJlcG9zaXRvcnkzMzQzMzEyODI=", StarredAt: time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC), Lists: []string{}},
+		},
+	}, export)
+}
+
+func TestWriteStarsExport(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	export := NewStarsExport("alphawong", Repos(testStarredRows()), map[string][]string{
+		"victorspringer/http-cache": {"go"},
+	})
+	require.NoError(WriteStarsExport(filepath.Join(dir, "stars.json"), export))
+	f, err := os.Open(filepath.Join(dir, "stars.json"))
+	require.NoError(err)
+	defer f.Close()
+	actual, err := ReadStarsExport(f)
+	require.NoError(err)
+	require.Equal(export.Stars, actual.Stars)
+	require.True(export.ExportedAt.Equal(actual.ExportedAt))
+}
+
+func TestReadStarsExportWithUnknownVersion(t *testing.T) {
+	require := require.New(t)
+	_, err := ReadStarsExport(strings.NewReader(`{"version": 2, "stars": []}`))
+	require.EqualError(err, "Unsupported export version 2, want 1")
+}
+
+func TestImportStarsResumes(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "import")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "stars.json.state")
+
+	export := StarsExport{Version: ExportVersion, Stars: []ExportedStar{
+		{FullName: "a/one"}, {FullName: "a/gone"}, {FullName: "a/flaky"},
+	}}
+	starrer := &fakeStarrer{errs: map[string]error{
+		"a/gone":  ErrNotFound,
+		"a/flaky": errors.New("Unexpected status 502"),
+	}}
+	state, err := OpenImportState(statePath)
+	require.NoError(err)
+	summary, err := ImportStars(starrer, export, state)
+	require.NoError(err)
+	require.NoError(state.Close())
+	require.Equal(ImportSummary{
+		Starred: 1,
+		Missing: []string{"a/gone"},
+		Failed:  []StarResult{{FullName: "a/flaky", Action: ActionStar, Result: StarResultFailed, Error: "Unexpected status 502"}},
+	}, summary)
+
+	// the second run only retries the failed repository
+	starrer = &fakeStarrer{}
+	state, err = OpenImportState(statePath)
+	require.NoError(err)
+	defer state.Close()
+	summary, err = ImportStars(starrer, export, state)
+	require.NoError(err)
+	require.Equal([]string{"a/flaky"}, starrer.starred)
+	require.Equal(ImportSummary{Starred: 1, Skipped: 1, Missing: []string{"a/gone"}}, summary)
+}
+
+func TestImportStarsWithRenamedRepository(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "import")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	export := StarsExport{Version: ExportVersion, Stars: []ExportedStar{
+		{FullName: "a/old-name", NodeID: "NODE42"}, {FullName: "a/gone", NodeID: "NODE7"},
+	}}
+	starrer := &fakeResolver{
+		fakeStarrer: fakeStarrer{errs: map[string]error{"a/old-name": ErrNotFound, "a/gone": ErrNotFound}},
+		names:       map[string]string{"NODE42": "a/new-name"},
+	}
+	state, err := OpenImportState(filepath.Join(dir, "stars.json.state"))
+	require.NoError(err)
+	defer state.Close()
+	summary, err := ImportStars(starrer, export, state)
+	require.NoError(err)
+	require.Equal([]string{"a/old-name", "a/new-name", "a/gone"}, starrer.starred)
+	require.Equal(ImportSummary{
+		Starred: 1,
+		Missing: []string{"a/gone"},
+		Renamed: map[string]string{"a/old-name": "a/new-name"},
+	}, summary)
+	require.Equal(ImportStarred, state.Result("a/old-name"))
+}
+
+func TestImportStarsWithLists(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "import")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	export := StarsExport{Version: ExportVersion, Stars: []ExportedStar{
+		{FullName: "a/listed", NodeID: "NODE1", Lists: []string{"go", "web"}},
+		{FullName: "a/unlisted", NodeID: "NODE2", Lists: []string{}},
+		{FullName: "a/flaky", NodeID: "NODE3", Lists: []string{"go"}},
+	}}
+	starrer := &fakeListStarrer{
+		listErrs: map[string]error{"NODE3": errors.New("GraphQL error: timeout")},
+		lists:    make(map[string][]string),
+	}
+	state, err := OpenImportState(filepath.Join(dir, "stars.json.state"))
+	require.NoError(err)
+	defer state.Close()
+	summary, err := ImportStars(starrer, export, state)
+	require.NoError(err)
+	require.Equal(map[string][]string{"NODE1": {"go", "web"}}, starrer.lists)
+	require.Equal(ImportSummary{
+		Starred: 2,
+		Failed:  []StarResult{{FullName: "a/flaky", Action: ActionStar, Result: StarResultFailed, Error: "GraphQL error: timeout"}},
+	}, summary)
+	// the lists are retried along with the star
+	require.Equal(ImportFailed, state.Result("a/flaky"))
+}
+
+func TestWriteImportSummary(t *testing.T) {
+	require := require.New(t)
+	var output strings.Builder
+	require.NoError(WriteImportSummary(&output, ImportSummary{
+		Starred: 2,
+		Missing: []string{"a/gone"},
+		Failed:  []StarResult{{FullName: "a/flaky", Error: "Unexpected status 502"}},
+		Renamed: map[string]string{"a/old-name": "a/new-name"},
+	}))
+	require.Equal("2 starred, 0 already starred, 1 missing, 1 failed\nrenamed a/old-name to a/new-name\nmissing a/gone\nfailed a/flaky: Unexpected status 502\n", output.String())
+}
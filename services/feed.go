@@ -0,0 +1,300 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	ErrorFeedFormat   = "Unknown feed format"
+	ErrorFeedUserName = "Missing feed user name"
+
+	FeedAtom = "atom"
+	FeedRSS  = "rss"
+
+	DefaultFeedLimit = 50
+
+	AtomNamespace = "http://www.w3.org/2005/Atom"
+	// FeedEntryID is GitHub's own tag URI for a repository, it survives renames
+	FeedEntryID   = "tag:github.com,2008:Repository/%d"
+	FeedID        = "tag:github.com,2008:Stars/%s"
+	FeedTitle     = "%s's stars"
+	FeedLink      = "https://github.com/%s?tab=stars"
+	FeedAuthorURI = "https://github.com/%s"
+)
+
+// ensure interface implement is correct
+var _ Renderer = (*FeedPrinter)(nil)
+
+type FeedPrinterOption func(*FeedPrinter)
+
+// WithFeedFormat selects FeedAtom or FeedRSS
+func WithFeedFormat(format string) FeedPrinterOption {
+	return func(feedPrinter *FeedPrinter) {
+		feedPrinter.Format = format
+	}
+}
+
+func WithFeedUserName(userName string) FeedPrinterOption {
+	return func(feedPrinter *FeedPrinter) {
+		feedPrinter.UserName = userName
+	}
+}
+
+// WithFeedLimit caps the number of entries, 0 means no limit
+func WithFeedLimit(limit int) FeedPrinterOption {
+	return func(feedPrinter *FeedPrinter) {
+		feedPrinter.Limit = limit
+	}
+}
+
+func WithFeedOutputPath(outputPath string) FeedPrinterOption {
+	return func(feedPrinter *FeedPrinter) {
+		feedPrinter.OutputPath = outputPath
+	}
+}
+
+// FeedPrinter writes the most recently starred repositories as an Atom 1.0
+// or RSS 2.0 feed.
+type FeedPrinter struct {
+	Format     string
+	UserName   string
+	Limit      int
+	OutputPath string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Updated   string         `xml:"updated"`
+	Published string         `xml:"published"`
+	Link      atomLink       `xml:"link"`
+	Category  []atomCategory `xml:"category"`
+	Summary   atomText       `xml:"summary"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Body        string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Category    []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+func NewFeedPrinter(setters ...FeedPrinterOption) (*FeedPrinter, error) {
+	feedPrinter := &FeedPrinter{
+		Format:     FeedAtom,
+		UserName:   "",
+		Limit:      DefaultFeedLimit,
+		OutputPath: "",
+	}
+
+	for _, setter := range setters {
+		setter(feedPrinter)
+	}
+
+	if feedPrinter.Format != FeedAtom && feedPrinter.Format != FeedRSS {
+		return nil, errors.New(ErrorFeedFormat)
+	}
+
+	if feedPrinter.UserName == "" {
+		return nil, errors.New(ErrorFeedUserName)
+	}
+
+	if feedPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return feedPrinter, nil
+}
+
+func (self *FeedPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *FeedPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *FeedPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	repositories := RecentlyStarred(markDownRows, self.Limit)
+	var feed interface{}
+	if self.Format == FeedRSS {
+		feed = self.rss(repositories)
+	} else {
+		feed = self.atom(repositories)
+	}
+	if _, err := io.WriteString(wr, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(wr)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(wr, "\n")
+	return err
+}
+
+func (self *FeedPrinter) atom(repositories UserStarredRepositories) atomFeed {
+	feed := atomFeed{
+		Xmlns: AtomNamespace,
+		ID:    fmt.Sprintf(FeedID, self.UserName),
+		Title: fmt.Sprintf(FeedTitle, self.UserName),
+		Link: []atomLink{
+			{Rel: "alternate", Href: fmt.Sprintf(FeedLink, self.UserName)},
+		},
+		Author: atomAuthor{
+			Name: self.UserName,
+			URI:  fmt.Sprintf(FeedAuthorURI, self.UserName),
+		},
+		Updated: feedUpdated(repositories).Format(time.RFC3339),
+	}
+	for _, v := range repositories {
+		entry := atomEntry{
+			ID:        fmt.Sprintf(FeedEntryID, v.ID),
+			Title:     v.FullName,
+			Updated:   v.StarredAt.UTC().Format(time.RFC3339),
+			Published: v.StarredAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Href: v.HTMLURL},
+			Summary:   atomText{Type: "text", Body: FeedSummary(v)},
+		}
+		if v.Language != "" {
+			entry.Category = []atomCategory{{Term: v.Language}}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+func (self *FeedPrinter) rss(repositories UserStarredRepositories) rssFeed {
+	channel := rssChannel{
+		Title:       fmt.Sprintf(FeedTitle, self.UserName),
+		Link:        fmt.Sprintf(FeedLink, self.UserName),
+		Description: fmt.Sprintf(FeedTitle, self.UserName),
+	}
+	if len(repositories) > 0 {
+		channel.LastBuildDate = feedUpdated(repositories).Format(time.RFC1123Z)
+	}
+	for _, v := range repositories {
+		item := rssItem{
+			Title:       v.FullName,
+			Link:        v.HTMLURL,
+			Description: FeedSummary(v),
+			GUID:        rssGUID{IsPermaLink: "false", Body: fmt.Sprintf(FeedEntryID, v.ID)},
+			PubDate:     v.StarredAt.UTC().Format(time.RFC1123Z),
+		}
+		if v.Language != "" {
+			item.Category = []string{v.Language}
+		}
+		channel.Items = append(channel.Items, item)
+	}
+	return rssFeed{Version: "2.0", Channel: channel}
+}
+
+// FeedSummary describes a repository in one paragraph for feed readers
+func FeedSummary(v Repository) string {
+	summary := v.Description
+	if summary == "" {
+		summary = v.FullName
+	}
+	if v.Language != "" {
+		summary = fmt.Sprintf("%s (%s, ★ %d)", summary, v.Language, v.StargazersCount)
+	} else {
+		summary = fmt.Sprintf("%s (★ %d)", summary, v.StargazersCount)
+	}
+	if v.Archived {
+		summary = summary + " [archived]"
+	}
+	return summary
+}
+
+// RecentlyStarred flattens the rows and returns up to limit repositories
+// with a known starred_at, most recently starred first.
+func RecentlyStarred(markDownRows []MarkDownRow, limit int) UserStarredRepositories {
+	var repositories UserStarredRepositories
+	for _, row := range markDownRows {
+		for _, v := range row.Repos {
+			if !v.StarredAt.IsZero() {
+				repositories = append(repositories, v)
+			}
+		}
+	}
+	sort.SliceStable(repositories, func(i, j int) bool {
+		if repositories[i].StarredAt.Equal(repositories[j].StarredAt) {
+			return repositories[i].FullName < repositories[j].FullName
+		}
+		return repositories[i].StarredAt.After(repositories[j].StarredAt)
+	})
+	if limit > 0 && len(repositories) > limit {
+		repositories = repositories[:limit]
+	}
+	return repositories
+}
+
+// feedUpdated is the newest starred_at so that regenerating an unchanged
+// feed produces identical bytes.
+func feedUpdated(repositories UserStarredRepositories) time.Time {
+	if len(repositories) == 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return repositories[0].StarredAt.UTC()
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in mock_data")
+
+// requireGolden compares actual with ../mock_data/<name>, rewriting the
+// file instead when the tests run with -update.
+func requireGolden(t *testing.T, name string, actual string) {
+	require := require.New(t)
+	path, err := filepath.Abs(filepath.Join("../mock_data", name))
+	require.NoError(err)
+	if *update {
+		require.NoError(ioutil.WriteFile(path, []byte(actual), 0644))
+	}
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Equal(string(expected), actual)
+}
+
+func TestNewFeedPrinterWithUnknownFormat(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewFeedPrinter(
+		WithFeedFormat("json"),
+		WithFeedUserName("alphawong"),
+		WithFeedOutputPath("feed.xml"),
+	)
+	require.EqualError(err, ErrorFeedFormat)
+	require.Nil(printer)
+}
+
+func TestNewFeedPrinterWithEmptyUserName(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewFeedPrinter(
+		WithFeedOutputPath("feed.xml"),
+	)
+	require.EqualError(err, ErrorFeedUserName)
+	require.Nil(printer)
+}
+
+func TestFeedPrinterPrintAtom(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewFeedPrinter(
+		WithFeedUserName("alphawong"),
+		WithFeedOutputPath("feed.xml"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	requireGolden(t, "feed.atom.golden", output.String())
+
+	var feed atomFeed
+	require.NoError(xml.Unmarshal([]byte(output.String()), &feed))
+	require.Equal(AtomNamespace, feed.XMLName.Space)
+	require.Len(feed.Entries, 2)
+	require.Equal("tag:github.com,2008:Repository/334331282", feed.Entries[0].ID)
+}
+
+func TestFeedPrinterPrintRSS(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewFeedPrinter(
+		WithFeedFormat(FeedRSS),
+		WithFeedUserName("alphawong"),
+		WithFeedLimit(1),
+		WithFeedOutputPath("feed.xml"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	requireGolden(t, "feed.rss.golden", output.String())
+
+	var feed rssFeed
+	require.NoError(xml.Unmarshal([]byte(output.String()), &feed))
+	require.Len(feed.Channel.Items, 1)
+	require.Equal("stefanwuthrich/cached-google-places", feed.Channel.Items[0].Title)
+}
+
+func TestRecentlyStarred(t *testing.T) {
+	require := require.New(t)
+	rows := testStarredRows()
+	unknown := rows[0].Repos[0]
+	unknown.StarredAt = unknown.StarredAt.AddDate(-1, 0, 0)
+	rows[0].Repos = append(rows[0].Repos, unknown, Repository{FullName: "no/starred-at"})
+
+	actual := RecentlyStarred(rows, 0)
+	require.Len(actual, 3)
+	require.Equal("stefanwuthrich/cached-google-places", actual[0].FullName)
+	require.Equal(rows[0].Repos[0], actual[1])
+	require.Equal(unknown, actual[2])
+	require.Len(RecentlyStarred(rows, 2), 2)
+}
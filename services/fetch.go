@@ -22,15 +22,26 @@ const (
 
 	Others       = "Others"
 	MarkdownStar = "[ [%s](%s) ]"
+
+	// StarMediaType asks GitHub to include starred_at with every repository
+	StarMediaType = "application/vnd.github.v3.star+json"
+
+	// GithubRateLimitURI reports the rate limit, calling it is free
+	GithubRateLimitURI = "https://api.github.com/rate_limit"
+
+	ErrorRateLimited = "Rate limited until %s"
+	ErrorFetchStatus = "Unexpected status %d from %s"
 )
 
 type Fetcher interface {
-	GetUsersStars() []MarkDownRow
+	GetUsersStars() ([]MarkDownRow, error)
+	GetUsersRepositories() (UserStarredRepositories, error)
 }
 
 type GitHubFetcher struct {
 	Token    string
 	UserName string
+	GroupKey GroupKey
 	H        *http.Client
 }
 
@@ -56,10 +67,18 @@ func WithUserName(name string) GitHubFetcherOption {
 	}
 }
 
+// WithGroupKey changes how GetUsersStars groups repositories, see GroupKeys
+func WithGroupKey(key GroupKey) GitHubFetcherOption {
+	return func(g *GitHubFetcher) {
+		g.GroupKey = key
+	}
+}
+
 func NewGitHubFetcher(setters ...GitHubFetcherOption) (*GitHubFetcher, error) {
 	g := &GitHubFetcher{
 		Token:    "",
 		UserName: "",
+		GroupKey: LanguageKey,
 		H:        &http.Client{},
 	}
 
@@ -78,35 +97,117 @@ func NewGitHubFetcher(setters ...GitHubFetcherOption) (*GitHubFetcher, error) {
 	return g, nil
 }
 
-func (self *GitHubFetcher) GetUsersStars() []MarkDownRow {
-	totalPageCount := self.GetUserStarredRepositoriesTotalPage()
-	starredRepositories := self.GetUserAllStarredRepositories(totalPageCount)
-	repositories := GroupByProgrammingLanguage(starredRepositories)
-	slices := Covert2Slice(repositories)
-	return slices
+func (self *GitHubFetcher) GetUsersStars() ([]MarkDownRow, error) {
+	userStarredRepositories, err := self.GetUsersRepositories()
+	if err != nil {
+		return nil, err
+	}
+	return Repositories2Slice(userStarredRepositories, self.GroupKey), nil
 }
 
-func (self *GitHubFetcher) GetUserStarredRepositoriesTotalPage() (totalPage int) {
-	rawURI := fmt.Sprintf(GithubURI, self.UserName)
-	u, err := url.Parse(rawURI)
+// GetUsersRepositories returns every starred repository, ungrouped
+func (self *GitHubFetcher) GetUsersRepositories() (UserStarredRepositories, error) {
+	defer DefaultMetrics.Since(MetricFetchDuration, time.Now())
+	totalPageCount, err := self.GetUserStarredRepositoriesTotalPage()
 	if err != nil {
-		log.Print(err.Error())
+		return nil, err
 	}
+	return self.GetUserAllStarredRepositories(totalPageCount)
+}
+
+// Repositories2Slice groups repositories by key into sorted rows
+func Repositories2Slice(userStarredRepositories UserStarredRepositories, key GroupKey) []MarkDownRow {
+	repositories := GroupByKey(userStarredRepositories, key)
+	slices := Covert2Slice(repositories)
+	return AttachRepositories(slices, userStarredRepositories, key)
+}
+
+func (self *GitHubFetcher) GetUserStarredRepositoriesTotalPage() (int, error) {
 	query := url.Values{
 		"per_page": []string{"100"},
 		"page":     []string{"1"},
 	}
-	u.RawQuery = query.Encode()
-	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+	uri, err := GetURI(GithubURI, self.UserName, query)
+	if err != nil {
+		return 0, err
+	}
+	req, _ := http.NewRequest(http.MethodGet, uri, nil)
 	req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Accept", StarMediaType)
 	resp, err := self.H.Do(req)
 	if err != nil {
-		log.Fatalf("%s", err)
+		return 0, err
+	}
+	resp.Body.Close()
+	observeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return 0, responseError(resp, uri)
 	}
 	linkHeader := resp.Header.Get("link")
-	totalPage = ParseRawLinkHeader(linkHeader)
-	return
+	if linkHeader == "" {
+		// GitHub leaves the link header out when there is a single page
+		return 1, nil
+	}
+	return ParseRawLinkHeader(linkHeader), nil
+}
+
+// responseError is the error of an unexpected response to uri, a
+// RateLimitError when GitHub rate limited the request
+func responseError(resp *http.Response, uri string) error {
+	reset := rateLimitReset(resp.Header)
+	if !reset.IsZero() || resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{Reset: reset}
+	}
+	return fmt.Errorf(ErrorFetchStatus, resp.StatusCode, uri)
+}
+
+// RateLimit is the core rate limit of the token
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitError is returned when too few requests are left, Reset is when
+// GitHub restores the limit
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (self *RateLimitError) Error() string {
+	return fmt.Sprintf(ErrorRateLimited, self.Reset.Format(time.RFC3339))
+}
+
+// GetRateLimit asks GitHub how many requests the token has left
+func (self *GitHubFetcher) GetRateLimit() (RateLimit, error) {
+	req, _ := http.NewRequest(http.MethodGet, GithubRateLimitURI, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
+	resp, err := self.H.Do(req)
+	if err != nil {
+		return RateLimit{}, err
+	}
+	defer resp.Body.Close()
+	observeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return RateLimit{}, fmt.Errorf(ErrorFetchStatus, resp.StatusCode, GithubRateLimitURI)
+	}
+
+	var body struct {
+		Rate struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
+		} `json:"rate"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return RateLimit{}, err
+	}
+	DefaultMetrics.Set(MetricRateLimit, float64(body.Rate.Remaining), "")
+	return RateLimit{
+		Limit:     body.Rate.Limit,
+		Remaining: body.Rate.Remaining,
+		Reset:     time.Unix(body.Rate.Reset, 0),
+	}, nil
 }
 
 func ParseRawLinkHeader(rawHeader string) (totalPage int) {
@@ -138,65 +239,85 @@ func GetURI(baseURI string, userName string, query url.Values) (string, error) {
 	return u.String(), nil
 }
 
-func (self *GitHubFetcher) GetUserAllStarredRepositories(totalPage int) (userStarredRepositories UserStarredRepositories) {
+// GetUserAllStarredRepositories fetches the pages concurrently, the first
+// failed page fails the fetch. The repositories are in the page order
+// whatever the order the pages arrive in, so that identical stars render
+// and hash the same.
+func (self *GitHubFetcher) GetUserAllStarredRepositories(totalPage int) (UserStarredRepositories, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*1)
 	defer cancel()
-	ch := make(chan UserStarredRepositories, totalPage)
+	type page struct {
+		num          int
+		repositories UserStarredRepositories
+		err          error
+	}
+	// buffered so that the pages still running after a failure do not block
+	ch := make(chan page, totalPage)
 	for i := 1; i <= totalPage; i++ {
 		go func(pageNum int) {
-			// put the uri construction here to avoid data race
-			query := url.Values{
-				"per_page": []string{"100"},
-				"page":     []string{strconv.Itoa(pageNum)},
-			}
-			uri, err := GetURI(GithubURI, self.UserName, query)
-			if err != nil {
-				log.Print(err.Error())
-			}
-			req, _ := http.NewRequest(http.MethodGet, uri, nil)
-			req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
-			req.Header.Set("Accept", "application/vnd.github.v3+json")
-			resp, err := self.H.Do(req)
-			if err != nil {
-				log.Fatalf("%s", err)
-			}
-			defer resp.Body.Close()
-
-			var singleUserStarredRepositoriesResponse UserStarredRepositories
-			err = json.NewDecoder(resp.Body).Decode(&singleUserStarredRepositoriesResponse)
-			if err != nil {
-				log.Fatalf("%s", err)
-			}
-			ch <- singleUserStarredRepositoriesResponse
+			repositories, err := self.getStarredPage(ctx, pageNum)
+			ch <- page{num: pageNum, repositories: repositories, err: err}
 		}(i)
 	}
-	var taskProgress = 0
-task:
-	for true {
+	pages := make([]UserStarredRepositories, totalPage)
+	for taskProgress := 0; taskProgress < totalPage; taskProgress++ {
 		select {
-		case repositories := <-ch:
-			userStarredRepositories = append(userStarredRepositories, repositories...)
-			taskProgress = taskProgress + 1
-			if taskProgress == totalPage {
-				break task
+		case page := <-ch:
+			if page.err != nil {
+				return nil, page.err
 			}
+			pages[page.num-1] = page.repositories
 		case <-ctx.Done():
-			break task
+			return nil, ctx.Err()
 		}
 	}
-	return userStarredRepositories
+	var userStarredRepositories UserStarredRepositories
+	for _, repositories := range pages {
+		userStarredRepositories = append(userStarredRepositories, repositories...)
+	}
+	return userStarredRepositories, nil
+}
+
+func (self *GitHubFetcher) getStarredPage(ctx context.Context, pageNum int) (UserStarredRepositories, error) {
+	query := url.Values{
+		"per_page": []string{"100"},
+		"page":     []string{strconv.Itoa(pageNum)},
+	}
+	uri, err := GetURI(GithubURI, self.UserName, query)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
+	req.Header.Set("Accept", StarMediaType)
+	resp, err := self.H.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	observeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, uri)
+	}
+
+	var singleUserStarredRepositoriesResponse UserStarredRepositories
+	if err := json.NewDecoder(resp.Body).Decode(&singleUserStarredRepositoriesResponse); err != nil {
+		return nil, err
+	}
+	DefaultMetrics.Add(MetricPages, 1, "")
+	return singleUserStarredRepositoriesResponse, nil
 }
 
 func GroupByProgrammingLanguage(userStarredRepositories UserStarredRepositories) map[string][]MarkDownRepo {
+	return GroupByKey(userStarredRepositories, LanguageKey)
+}
+
+func GroupByKey(userStarredRepositories UserStarredRepositories, key GroupKey) map[string][]MarkDownRepo {
 	var repositories = make(map[string][]MarkDownRepo)
 	for _, v := range userStarredRepositories {
-		var languageKey = v.Language
-		if v.Language == "" {
-			// handle repo without any language categorizing
-			languageKey = Others
-		}
-		repositories[languageKey] = append(
-			repositories[languageKey],
+		var groupKey = key(v)
+		repositories[groupKey] = append(
+			repositories[groupKey],
 			MarkDownRepo{
 				FullName: v.FullName,
 				HtmlUrl:  v.HTMLURL,
@@ -208,6 +329,53 @@ func GroupByProgrammingLanguage(userStarredRepositories UserStarredRepositories)
 	return repositories
 }
 
+// GroupKey names the group a starred repository belongs to
+type GroupKey func(Repository) string
+
+// GroupKeys maps the supported grouping names to their GroupKey
+var GroupKeys = map[string]GroupKey{
+	"language": LanguageKey,
+	"topic":    TopicKey,
+	"owner":    OwnerKey,
+}
+
+func LanguageKey(v Repository) string {
+	if v.Language == "" {
+		// handle repo without any language categorizing
+		return Others
+	}
+	return v.Language
+}
+
+// TopicKey groups by the first topic, GitHub returns topics sorted
+func TopicKey(v Repository) string {
+	if len(v.Topics) == 0 {
+		return Others
+	}
+	return v.Topics[0]
+}
+
+func OwnerKey(v Repository) string {
+	if v.Owner.Login == "" {
+		return Others
+	}
+	return v.Owner.Login
+}
+
+// AttachRepositories fills MarkDownRow.Repos with the repositories of each
+// row, in the same order as they appear in Items.
+func AttachRepositories(rows []MarkDownRow, userStarredRepositories UserStarredRepositories, key GroupKey) []MarkDownRow {
+	var repositories = make(map[string]UserStarredRepositories)
+	for _, v := range userStarredRepositories {
+		var groupKey = key(v)
+		repositories[groupKey] = append(repositories[groupKey], v)
+	}
+	for i := range rows {
+		rows[i].Repos = repositories[rows[i].Language]
+	}
+	return rows
+}
+
 func Covert2Slice(repositories map[string][]MarkDownRepo) []MarkDownRow {
 	keys := GetMapKeyASC(repositories)
 	var rows = make([]MarkDownRow, 0, len(keys))
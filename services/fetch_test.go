@@ -2,14 +2,17 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/require"
@@ -68,7 +71,8 @@ func TestGetUserStarredRepositoriesTotalPage(t *testing.T) {
 			return resp, nil
 		},
 	)
-	actual := fetcher.GetUserStarredRepositoriesTotalPage()
+	actual, err := fetcher.GetUserStarredRepositoriesTotalPage()
+	require.NoError(err)
 	require.Equal(63, actual)
 }
 
@@ -144,7 +148,8 @@ func TestGetUserAllStarredRepositories(t *testing.T) {
 			httpmock.File(response2Path),
 		),
 	)
-	actual := fetcher.GetUserAllStarredRepositories(2)
+	actual, err := fetcher.GetUserAllStarredRepositories(2)
+	require.NoError(err)
 	path, err := filepath.Abs("../mock_data/page_total.json")
 	require.NoError(err)
 	b, err := ioutil.ReadFile(path)
@@ -155,6 +160,51 @@ func TestGetUserAllStarredRepositories(t *testing.T) {
 	require.Contains(expected, actual[1])
 }
 
+func TestGetUserAllStarredRepositoriesKeepsPageOrder(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	fetcher, err := NewGitHubFetcher(
+		WithToken("TOKEN"),
+		WithUserName("alphawong"),
+	)
+	require.NoError(err)
+	// every page is answered once the next one was, so that the pages
+	// arrive last to first
+	const totalPage = 3
+	answered := make([]chan struct{}, totalPage+1)
+	for i := range answered {
+		answered[i] = make(chan struct{})
+	}
+	close(answered[totalPage])
+	for i := 1; i <= totalPage; i++ {
+		pageNum := i
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			"https://api.github.com/users/alphawong/starred?page="+strconv.Itoa(pageNum)+"&per_page=100",
+			func(req *http.Request) (*http.Response, error) {
+				<-answered[pageNum]
+				defer close(answered[pageNum-1])
+				return httpmock.NewJsonResponse(http.StatusOK, []Repository{
+					{FullName: "a/page-" + strconv.Itoa(pageNum) + "-first"},
+					{FullName: "a/page-" + strconv.Itoa(pageNum) + "-second"},
+				})
+			},
+		)
+	}
+	actual, err := fetcher.GetUserAllStarredRepositories(totalPage)
+	require.NoError(err)
+	var fullNames []string
+	for _, repository := range actual {
+		fullNames = append(fullNames, repository.FullName)
+	}
+	require.Equal([]string{
+		"a/page-1-first", "a/page-1-second",
+		"a/page-2-first", "a/page-2-second",
+		"a/page-3-first", "a/page-3-second",
+	}, fullNames)
+}
+
 func TestGroupByProgrammingLanguage(t *testing.T) {
 	require := require.New(t)
 	httpmock.Activate()
@@ -196,7 +246,8 @@ func TestGroupByProgrammingLanguage(t *testing.T) {
 			httpmock.File(response3Path),
 		),
 	)
-	repos := fetcher.GetUserAllStarredRepositories(3)
+	repos, err := fetcher.GetUserAllStarredRepositories(3)
+	require.NoError(err)
 	grouped := GroupByProgrammingLanguage(repos)
 	require.Contains(grouped["Go"], MarkDownRepo{
 		FullName: "victorspringer/http-cache",
@@ -278,3 +329,149 @@ func TestGetURIWithInvalidBaseURI(t *testing.T) {
 	require.Error(err, "missing protocol scheme")
 	require.Equal("", actual)
 }
+
+func TestRepositoryUnmarshalJSONWithStarMediaType(t *testing.T) {
+	require := require.New(t)
+	var repos UserStarredRepositories
+	err := json.Unmarshal([]byte(`[
+		{"starred_at": "2021-03-18T09:30:00Z", "repo": {"full_name": "victorspringer/http-cache", "topics": ["cache"]}},
+		{"full_name": "stefanwuthrich/cached-google-places"}
+	]`), &repos)
+	require.NoError(err)
+	require.Equal("victorspringer/http-cache", repos[0].FullName)
+	require.Equal([]string{"cache"}, repos[0].Topics)
+	require.Equal(time.Date(2021, 3, 18, 9, 30, 0, 0, time.UTC), repos[0].StarredAt)
+	require.Equal("stefanwuthrich/cached-google-places", repos[1].FullName)
+	require.True(repos[1].StarredAt.IsZero())
+}
+
+func TestAttachRepositories(t *testing.T) {
+	require := require.New(t)
+	repos := UserStarredRepositories{
+		{FullName: "a/go", Language: "Go"},
+		{FullName: "b/none"},
+		{FullName: "c/go", Language: "Go"},
+	}
+	rows := Covert2Slice(GroupByProgrammingLanguage(repos))
+	rows = AttachRepositories(rows, repos, LanguageKey)
+	require.Len(rows, 2)
+	require.Equal("Go", rows[0].Language)
+	require.Equal(UserStarredRepositories{repos[0], repos[2]}, rows[0].Repos)
+	require.Equal(Others, rows[1].Language)
+	require.Equal(UserStarredRepositories{repos[1]}, rows[1].Repos)
+}
+
+func TestGroupByKey(t *testing.T) {
+	require := require.New(t)
+	repos := UserStarredRepositories{
+		{FullName: "a/go", Language: "Go", Topics: []string{"cache", "http"}},
+		{FullName: "b/none"},
+	}
+	repos[0].Owner.Login = "a"
+	byTopic := GroupByKey(repos, GroupKeys["topic"])
+	require.Equal([]string{Others, "cache"}, GetMapKeyASC(byTopic))
+	byOwner := GroupByKey(repos, GroupKeys["owner"])
+	require.Equal([]string{Others, "a"}, GetMapKeyASC(byOwner))
+	require.Equal("a/go", byOwner["a"][0].FullName)
+}
+
+func TestGetRateLimit(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		GithubRateLimitURI,
+		httpmock.NewStringResponder(http.StatusOK, `{"resources":{},"rate":{"limit":5000,"remaining":12,"reset":1792368000,"used":4988}}`),
+	)
+
+	fetcher, err := NewGitHubFetcher(WithToken("token"), WithUserName("alphawong"))
+	require.NoError(err)
+	requests := DefaultMetrics.Value(MetricRequests, "200")
+	limit, err := fetcher.GetRateLimit()
+	require.NoError(err)
+	require.Equal(RateLimit{Limit: 5000, Remaining: 12, Reset: time.Unix(1792368000, 0)}, limit)
+	require.Equal(requests+1, DefaultMetrics.Value(MetricRequests, "200"))
+	require.Equal(12.0, DefaultMetrics.Value(MetricRateLimit, ""))
+
+	err = &RateLimitError{Reset: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	require.EqualError(err, "Rate limited until 2026-10-19T12:00:00Z")
+}
+
+func TestGetRateLimitWithUnexpectedStatus(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodGet, GithubRateLimitURI, httpmock.NewStringResponder(http.StatusUnauthorized, `{}`))
+
+	fetcher, err := NewGitHubFetcher(WithToken("token"), WithUserName("alphawong"))
+	require.NoError(err)
+	_, err = fetcher.GetRateLimit()
+	require.EqualError(err, "Unexpected status 401 from "+GithubRateLimitURI)
+}
+
+func TestGetUsersRepositoriesWithFailedPage(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=1&per_page=100",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `[]`)
+			resp.Header.Set("link", `<https://api.github.com/user/5622516/starred?page=2>; rel="next", <https://api.github.com/user/5622516/starred?page=2>; rel="last"`)
+			return resp, nil
+		},
+	)
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=2&per_page=100",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusForbidden, `{"message": "API rate limit exceeded"}`)
+			resp.Header.Set("X-RateLimit-Remaining", "0")
+			resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			return resp, nil
+		},
+	)
+
+	fetcher, err := NewGitHubFetcher(WithToken("token"), WithUserName("alphawong"))
+	require.NoError(err)
+	_, err = fetcher.GetUsersRepositories()
+	var limited *RateLimitError
+	require.True(errors.As(err, &limited))
+	require.True(reset.Equal(limited.Reset))
+
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=2&per_page=100",
+		httpmock.NewStringResponder(http.StatusOK, `not json`),
+	)
+	_, err = fetcher.GetUsersRepositories()
+	require.Error(err)
+
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=1&per_page=100",
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, ``),
+	)
+	_, err = fetcher.GetUsersRepositories()
+	require.EqualError(err, "Unexpected status 503 from https://api.github.com/users/alphawong/starred?page=1&per_page=100")
+}
+
+func TestGetUserStarredRepositoriesTotalPageWithSinglePage(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=1&per_page=100",
+		httpmock.NewStringResponder(http.StatusOK, `[]`),
+	)
+
+	fetcher, err := NewGitHubFetcher(WithToken("token"), WithUserName("alphawong"))
+	require.NoError(err)
+	totalPage, err := fetcher.GetUserStarredRepositoriesTotalPage()
+	require.NoError(err)
+	require.Equal(1, totalPage)
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	ErrorFuncNumber = "%v is not a number"
+	ErrorFuncField  = "Unknown repository field %q"
+	ErrorFuncSortBy = "Unknown sort field %q"
+
+	DefaultLanguageIcon = "📄"
+)
+
+// now is replaced in tests to make timeago deterministic
+var now = time.Now
+
+// markdownEscaper escapes the characters that start inline markdown or end
+// a table cell
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`|`, `\|`,
+)
+
+var repoLess = map[string]func(a Repository, b Repository) bool{
+	"name": func(a Repository, b Repository) bool {
+		return strings.ToLower(a.FullName) < strings.ToLower(b.FullName)
+	},
+	"language":   func(a Repository, b Repository) bool { return a.Language < b.Language },
+	"stars":      func(a Repository, b Repository) bool { return a.StargazersCount < b.StargazersCount },
+	"forks":      func(a Repository, b Repository) bool { return a.ForksCount < b.ForksCount },
+	"starred_at": func(a Repository, b Repository) bool { return a.StarredAt.Before(b.StarredAt) },
+	"pushed_at":  func(a Repository, b Repository) bool { return a.PushedAt.Before(b.PushedAt) },
+}
+
+var languageIcons = map[string]string{
+	"Assembly":         "🔩",
+	"C":                "🔧",
+	"C#":               "🎼",
+	"C++":              "⚙️",
+	"CSS":              "🎨",
+	"Clojure":          "🌀",
+	"Dart":             "🎯",
+	"Dockerfile":       "🐳",
+	"Elixir":           "💧",
+	"Erlang":           "📞",
+	"Go":               "🐹",
+	"HCL":              "🏗️",
+	"HTML":             "🌐",
+	"Haskell":          "🎩",
+	"Java":             "☕",
+	"JavaScript":       "🟨",
+	"Julia":            "🔬",
+	"Jupyter Notebook": "📓",
+	"Kotlin":           "🟣",
+	"Lua":              "🌙",
+	"Makefile":         "🛠️",
+	"Nix":              "❄️",
+	"OCaml":            "🐫",
+	"Objective-C":      "🍎",
+	Others:             "📦",
+	"PHP":              "🐘",
+	"Perl":             "🐪",
+	"PowerShell":       "💻",
+	"Python":           "🐍",
+	"R":                "📊",
+	"Ruby":             "💎",
+	"Rust":             "🦀",
+	"Scala":            "🔺",
+	"Shell":            "🐚",
+	"Solidity":         "💠",
+	"Svelte":           "🧡",
+	"Swift":            "🐦",
+	"TeX":              "📐",
+	"TypeScript":       "🔷",
+	"Vim script":       "📝",
+	"Vue":              "💚",
+	"Zig":              "⚡",
+}
+
+// TemplateFuncs is the function library registered on every template based
+// printer, e.g.
+//
+//	{{range repos . | where "language" "Go" | sortby "-stars" | first 10}}
+//	{{.FullName}} ⭐️ {{humanize .StargazersCount}}, starred {{timeago .StarredAt}}
+//	{{end}}
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"humanize":   Humanize,
+		"timeago":    TimeAgo,
+		"slug":       Slugify,
+		"anchor":     GitHubAnchor,
+		"truncate":   Truncate,
+		"mdescape":   EscapeMarkdown,
+		"htmlescape": html.EscapeString,
+		"pluralize":  Pluralize,
+		"langicon":   LanguageIcon,
+		"repos":      Repos,
+		"sortby":     SortRepos,
+		"where":      WhereRepos,
+		"groupby":    GroupRepos,
+		"first":      FirstRepos,
+		"chart":      ChartPath,
+		"unhealthy":  Unhealthy,
+		"contents":   func() string { return MarkdownContents },
+		// TplPrinter rebinds changes to the delta against its previous
+		// snapshot
+		"changes": func() Changelog { return NewChangelog(now(), nil, nil) },
+	}
+}
+
+func toInt(n interface{}) (int, error) {
+	switch v := n.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf(ErrorFuncNumber, n)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf(ErrorFuncNumber, n)
+	}
+}
+
+// Humanize shortens a count the way GitHub does, e.g. 12345 to 12.3k
+func Humanize(n interface{}) (string, error) {
+	i, err := toInt(n)
+	if err != nil {
+		return "", err
+	}
+	sign := ""
+	if i < 0 {
+		sign, i = "-", -i
+	}
+	if i < 1000 {
+		return sign + strconv.Itoa(i), nil
+	}
+	units := []string{"k", "M", "B"}
+	value := float64(i)
+	for index, unit := range units {
+		value = value / 1000
+		rounded := math.Round(value*10) / 10
+		// 999950 rounds to 1000.0k, which reads better as 1M
+		if rounded < 1000 || index == len(units)-1 {
+			return sign + strconv.FormatFloat(rounded, 'f', -1, 64) + unit, nil
+		}
+	}
+	return "", nil
+}
+
+// TimeAgo describes t relative to now, e.g. "3 days ago" or "in 2 hours"
+func TimeAgo(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now().Sub(t)
+	format := "%s ago"
+	if d < 0 {
+		d = -d
+		format = "in %s"
+	}
+	var amount int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		amount, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		amount, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		amount, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		amount, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if amount != 1 {
+		unit = unit + "s"
+	}
+	return fmt.Sprintf(format, fmt.Sprintf("%d %s", amount, unit))
+}
+
+// Truncate shortens s to at most n characters, ending with an ellipsis
+func Truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// Pluralize returns singular when n is 1 and plural otherwise
+func Pluralize(n interface{}, singular string, plural string) (string, error) {
+	i, err := toInt(n)
+	if err != nil {
+		return "", err
+	}
+	if i == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+func LanguageIcon(language string) string {
+	if icon, ok := languageIcons[language]; ok {
+		return icon
+	}
+	return DefaultLanguageIcon
+}
+
+// Repos flattens rows into their repositories
+func Repos(markDownRows []MarkDownRow) UserStarredRepositories {
+	var repositories UserStarredRepositories
+	for _, row := range markDownRows {
+		repositories = append(repositories, row.Repos...)
+	}
+	return repositories
+}
+
+// SortRepos returns a sorted copy, prefix field with - to sort descending
+func SortRepos(field string, repositories UserStarredRepositories) (UserStarredRepositories, error) {
+	descending := strings.HasPrefix(field, "-")
+	less, ok := repoLess[strings.TrimPrefix(field, "-")]
+	if !ok {
+		return nil, fmt.Errorf(ErrorFuncSortBy, field)
+	}
+	sorted := append(UserStarredRepositories(nil), repositories...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
+
+// WhereRepos keeps the repositories whose field equals value, topics match
+// when any topic equals value
+func WhereRepos(field string, value interface{}, repositories UserStarredRepositories) (UserStarredRepositories, error) {
+	get, ok := repoFields[field]
+	if !ok {
+		return nil, fmt.Errorf(ErrorFuncField, field)
+	}
+	want := fmt.Sprint(value)
+	var filtered UserStarredRepositories
+	for _, v := range repositories {
+		if field == "topics" {
+			for _, topic := range v.Topics {
+				if topic == want {
+					filtered = append(filtered, v)
+					break
+				}
+			}
+			continue
+		}
+		if get(v) == want {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, nil
+}
+
+// GroupRepos regroups repositories into rows by field, in the same shape as
+// the rows given to templates
+func GroupRepos(field string, repositories UserStarredRepositories) ([]MarkDownRow, error) {
+	get, ok := repoFields[field]
+	if !ok {
+		return nil, fmt.Errorf(ErrorFuncField, field)
+	}
+	key := func(v Repository) string {
+		if value := get(v); value != "" {
+			return value
+		}
+		return Others
+	}
+	return Repositories2Slice(repositories, key), nil
+}
+
+// FirstRepos returns the first n repositories, none when n is negative
+func FirstRepos(n int, repositories UserStarredRepositories) UserStarredRepositories {
+	if n < 0 {
+		n = 0
+	}
+	if n < len(repositories) {
+		return repositories[:n]
+	}
+	return repositories
+}
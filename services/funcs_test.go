@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHumanize(t *testing.T) {
+	require := require.New(t)
+	for input, expected := range map[interface{}]string{
+		0:          "0",
+		999:        "999",
+		1000:       "1k",
+		12345:      "12.3k",
+		"183":      "183",
+		999950:     "1M",
+		1234567:    "1.2M",
+		-4200:      "-4.2k",
+		int64(5e9): "5B",
+	} {
+		actual, err := Humanize(input)
+		require.NoError(err)
+		require.Equal(expected, actual, "%v", input)
+	}
+	_, err := Humanize("many")
+	require.EqualError(err, "many is not a number")
+}
+
+func TestTimeAgo(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	current := time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	require.Equal("", TimeAgo(time.Time{}))
+	require.Equal("just now", TimeAgo(current.Add(-time.Second)))
+	require.Equal("1 minute ago", TimeAgo(current.Add(-time.Minute)))
+	require.Equal("5 hours ago", TimeAgo(current.Add(-5*time.Hour)))
+	require.Equal("3 days ago", TimeAgo(current.AddDate(0, 0, -3)))
+	require.Equal("2 months ago", TimeAgo(current.AddDate(0, 0, -65)))
+	require.Equal("1 year ago", TimeAgo(current.AddDate(-1, 0, -1)))
+	require.Equal("in 2 hours", TimeAgo(current.Add(2*time.Hour)))
+}
+
+func TestFirstRepos(t *testing.T) {
+	require := require.New(t)
+	repositories := testStarredRows()[0].Repos
+	require.Len(FirstRepos(1, repositories), 1)
+	require.Len(FirstRepos(10, repositories), len(repositories))
+	require.Empty(FirstRepos(-1, repositories))
+}
+
+func TestTruncate(t *testing.T) {
+	require := require.New(t)
+	require.Equal("short", Truncate(10, "short"))
+	require.Equal("High…", Truncate(6, "High performance"))
+	require.Equal("星星星…", Truncate(4, "星星星星星"))
+	require.Equal("any", Truncate(0, "any"))
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	require := require.New(t)
+	require.Equal(`a\|b \*c\* \[d\](e) \<f\> \_g\_`, EscapeMarkdown("a|b *c* [d](e) <f> _g_"))
+}
+
+func TestPluralize(t *testing.T) {
+	require := require.New(t)
+	actual, err := Pluralize("1", "repo", "repos")
+	require.NoError(err)
+	require.Equal("repo", actual)
+	actual, err = Pluralize(3, "repo", "repos")
+	require.NoError(err)
+	require.Equal("repos", actual)
+}
+
+func TestLanguageIcon(t *testing.T) {
+	require := require.New(t)
+	require.Equal("🐹", LanguageIcon("Go"))
+	require.Equal("📦", LanguageIcon(Others))
+	require.Equal(DefaultLanguageIcon, LanguageIcon("Brainfuck"))
+}
+
+func TestSortRepos(t *testing.T) {
+	require := require.New(t)
+	repos := Repos(testStarredRows())
+	sorted, err := SortRepos("stars", repos)
+	require.NoError(err)
+	require.Equal("stefanwuthrich/cached-google-places", sorted[0].FullName)
+	sorted, err = SortRepos("-stars", repos)
+	require.NoError(err)
+	require.Equal("victorspringer/http-cache", sorted[0].FullName)
+	// the input is left untouched
+	require.Equal("victorspringer/http-cache", repos[0].FullName)
+	_, err = SortRepos("color", repos)
+	require.EqualError(err, `Unknown sort field "color"`)
+}
+
+func TestWhereRepos(t *testing.T) {
+	require := require.New(t)
+	repos := Repos(testStarredRows())
+	archived, err := WhereRepos("archived", true, repos)
+	require.NoError(err)
+	require.Len(archived, 1)
+	require.Equal("stefanwuthrich/cached-google-places", archived[0].FullName)
+	tagged, err := WhereRepos("topics", "golang", repos)
+	require.NoError(err)
+	require.Len(tagged, 1)
+	require.Equal("victorspringer/http-cache", tagged[0].FullName)
+	_, err = WhereRepos("color", "red", repos)
+	require.EqualError(err, `Unknown repository field "color"`)
+}
+
+func TestGroupRepos(t *testing.T) {
+	require := require.New(t)
+	rows, err := GroupRepos("license", Repos(testStarredRows()))
+	require.NoError(err)
+	require.Len(rows, 2)
+	require.Equal("MIT", rows[0].Language)
+	require.Equal(Others, rows[1].Language)
+	require.Equal("stefanwuthrich/cached-google-places", rows[1].Repos[0].FullName)
+}
+
+func TestTemplateFuncsInTemplate(t *testing.T) {
+	require := require.New(t)
+	tpl, err := template.New("tpl").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}{{range .}}## {{langicon .Language}} [{{.Language}}](#{{anchor .Language}}) {{humanize .Stars}} {{pluralize .Stars "repo" "repos"}}
+{{end}}{{range repos . | sortby "-stars" | first 1}}{{.FullName}} {{humanize .StargazersCount}} {{.Description | truncate 20 | mdescape}}{{end}}
+{{range groupby "archived" (repos .)}}{{.Language}}={{.Stars}} {{end}}{{end}}`)
+	require.NoError(err)
+	var output strings.Builder
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Equal("## 🐹 [Go](#go) 1 repo\n## 🟨 [JavaScript](#javascript) 1 repo\n"+
+		"victorspringer/http-cache 183 High performance Go…\n"+
+		"false=1 true=1 ", output.String())
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultCommitMessage summarises the stats of the committed output
+	DefaultCommitMessage = `Update stars of {{.UserName}}
+
+{{.Stats.Total}} {{pluralize .Stats.Total "repository" "repositories"}} in {{len .Stats.Languages}} {{pluralize (len .Stats.Languages) "language" "languages"}}, {{.Stats.Archived}} archived, {{.Stats.Stale}} stale
+`
+
+	ErrorGitDir    = "Missing git repository"
+	ErrorGitAuthor = "Invalid author %q, want Name <email>"
+	ErrorGit       = "git %s: %v: %s"
+)
+
+// CommitData is what the commit message template is executed with
+type CommitData struct {
+	UserName string
+	At       time.Time
+	Stats    Stats
+}
+
+type GitCommitterOption func(*GitCommitter)
+
+func WithGitDir(dir string) GitCommitterOption {
+	return func(g *GitCommitter) {
+		g.Dir = dir
+	}
+}
+
+// WithGitAuthor sets the author and committer as "Name <email>", git's own
+// configuration is used when empty
+func WithGitAuthor(author string) GitCommitterOption {
+	return func(g *GitCommitter) {
+		g.Author = author
+	}
+}
+
+// WithGitBranch commits onto branch, the current branch when empty
+func WithGitBranch(branch string) GitCommitterOption {
+	return func(g *GitCommitter) {
+		g.Branch = branch
+	}
+}
+
+// WithGitMessage sets the commit message template, see CommitData
+func WithGitMessage(message string) GitCommitterOption {
+	return func(g *GitCommitter) {
+		g.Message = message
+	}
+}
+
+// GitCommitter commits output files into the git repository at Dir with the
+// git command. It builds the commit in a temporary index so that committing
+// onto a branch other than the checked out one leaves the work tree alone.
+type GitCommitter struct {
+	Dir     string
+	Author  string
+	Branch  string
+	Message string
+
+	author  *mail.Address
+	message *template.Template
+}
+
+func NewGitCommitter(setters ...GitCommitterOption) (*GitCommitter, error) {
+	g := &GitCommitter{
+		Dir:     "",
+		Author:  "",
+		Branch:  "",
+		Message: DefaultCommitMessage,
+	}
+
+	for _, setter := range setters {
+		setter(g)
+	}
+
+	if g.Dir == "" {
+		return nil, errors.New(ErrorGitDir)
+	}
+	if g.Author != "" {
+		author, err := mail.ParseAddress(g.Author)
+		if err != nil {
+			return nil, fmt.Errorf(ErrorGitAuthor, g.Author)
+		}
+		g.author = author
+	}
+	message, err := template.New("message").Funcs(TemplateFuncs()).Parse(g.Message)
+	if err != nil {
+		return nil, err
+	}
+	g.message = message
+
+	return g, nil
+}
+
+// Commit commits the current content of paths, files or directories, onto
+// the branch. It commits nothing and returns false when paths are the same
+// as on the branch.
+func (self *GitCommitter) Commit(paths []string, data CommitData) (bool, error) {
+	branch, err := self.branch()
+	if err != nil {
+		return false, err
+	}
+	ref := "refs/heads/" + branch
+	parent, _ := self.git(nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+
+	index, err := ioutil.TempFile("", "stars-index.*")
+	if err != nil {
+		return false, err
+	}
+	index.Close()
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	if parent != "" {
+		if _, err := self.git(env, "read-tree", parent); err != nil {
+			return false, err
+		}
+	}
+	if _, err := self.git(env, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return false, err
+	}
+	tree, err := self.git(env, "write-tree")
+	if err != nil {
+		return false, err
+	}
+	if parent != "" {
+		if previous, _ := self.git(nil, "rev-parse", parent+"^{tree}"); previous == tree {
+			return false, nil
+		}
+	}
+
+	var message bytes.Buffer
+	if err := self.message.Execute(&message, data); err != nil {
+		return false, err
+	}
+	args := []string{"commit-tree", tree, "-F", "-"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := self.gitInput(self.identity(), &message, args...)
+	if err != nil {
+		return false, err
+	}
+	if _, err := self.git(nil, "update-ref", "-m", "stars: commit output", ref, commit, parent); err != nil {
+		return false, err
+	}
+
+	// the index of a checked out branch still holds the previous content
+	if current, _ := self.git(nil, "symbolic-ref", "--quiet", "HEAD"); current == ref {
+		if _, err := self.git(nil, append([]string{"reset", "-q", "--"}, paths...)...); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Push pushes the branch to remote, a name or a url
+func (self *GitCommitter) Push(remote string) error {
+	branch, err := self.branch()
+	if err != nil {
+		return err
+	}
+	_, err = self.git(nil, "push", "--quiet", remote, "refs/heads/"+branch+":refs/heads/"+branch)
+	return err
+}
+
+func (self *GitCommitter) branch() (string, error) {
+	if self.Branch != "" {
+		return self.Branch, nil
+	}
+	return self.git(nil, "symbolic-ref", "--quiet", "--short", "HEAD")
+}
+
+func (self *GitCommitter) identity() []string {
+	if self.author == nil {
+		return nil
+	}
+	name := self.author.Name
+	if name == "" {
+		name = self.author.Address
+	}
+	return []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + self.author.Address,
+		"GIT_COMMITTER_NAME=" + name,
+		"GIT_COMMITTER_EMAIL=" + self.author.Address,
+	}
+}
+
+func (self *GitCommitter) git(env []string, args ...string) (string, error) {
+	return self.gitInput(env, nil, args...)
+}
+
+// gitInput runs git in Dir and returns its trimmed output
+func (self *GitCommitter) gitInput(env []string, input *bytes.Buffer, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = self.Dir
+	cmd.Env = append(os.Environ(), env...)
+	if input != nil {
+		cmd.Stdin = input
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf(ErrorGit, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runGit runs git in dir for the assertions of the tests
+func runGit(require *require.Assertions, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+func testGitRepository(require *require.Assertions) string {
+	dir, err := ioutil.TempDir("", "repo")
+	require.NoError(err)
+	runGit(require, dir, "init", "--quiet", "-b", "main")
+	return dir
+}
+
+func testCommitData() CommitData {
+	return CommitData{
+		UserName: "alphawong",
+		At:       time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Stats:    ComputeStats(Repos(testStarredRows()), DefaultStatsTop, DefaultStaleAfter),
+	}
+}
+
+func TestNewGitCommitter(t *testing.T) {
+	require := require.New(t)
+	_, err := NewGitCommitter()
+	require.EqualError(err, ErrorGitDir)
+	_, err = NewGitCommitter(WithGitDir("."), WithGitAuthor("nobody"))
+	require.EqualError(err, `Invalid author "nobody", want Name <email>`)
+	_, err = NewGitCommitter(WithGitDir("."), WithGitMessage("{{.Nope"))
+	require.Error(err)
+}
+
+func TestGitCommitterCommit(t *testing.T) {
+	require := require.New(t)
+	dir := testGitRepository(require)
+	defer os.RemoveAll(dir)
+	outputPath := filepath.Join(dir, "README.md")
+	require.NoError(ioutil.WriteFile(outputPath, []byte("# Stars\n"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not mine\n"), 0644))
+
+	committer, err := NewGitCommitter(WithGitDir(dir), WithGitAuthor("Stars Bot <bot@example.com>"))
+	require.NoError(err)
+	committed, err := committer.Commit([]string{outputPath}, testCommitData())
+	require.NoError(err)
+	require.True(committed)
+
+	require.Equal("Stars Bot <bot@example.com>", runGit(require, dir, "log", "-1", "--format=%an <%ae>"))
+	require.Equal("Stars Bot", runGit(require, dir, "log", "-1", "--format=%cn"))
+	require.Equal("Update stars of alphawong\n\n2 repositories in 2 languages, 1 archived, 2 stale",
+		runGit(require, dir, "log", "-1", "--format=%B"))
+	require.Equal("README.md", runGit(require, dir, "ls-tree", "--name-only", "HEAD"))
+	// only the output is committed and the index agrees with HEAD
+	require.Equal("?? notes.txt", runGit(require, dir, "status", "--porcelain"))
+
+	committed, err = committer.Commit([]string{outputPath}, testCommitData())
+	require.NoError(err)
+	require.False(committed)
+	require.Equal("1", runGit(require, dir, "rev-list", "--count", "HEAD"))
+}
+
+func TestGitCommitterCommitWithBranchAndPush(t *testing.T) {
+	require := require.New(t)
+	dir := testGitRepository(require)
+	defer os.RemoveAll(dir)
+	remote, err := ioutil.TempDir("", "remote")
+	require.NoError(err)
+	defer os.RemoveAll(remote)
+	runGit(require, remote, "init", "--quiet", "--bare")
+
+	outputDir := filepath.Join(dir, "stars")
+	require.NoError(os.MkdirAll(outputDir, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(outputDir, "go.md"), []byte("# Go\n"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(outputDir, "rust.md"), []byte("# Rust\n"), 0644))
+
+	committer, err := NewGitCommitter(
+		WithGitDir(dir),
+		WithGitAuthor("Stars Bot <bot@example.com>"),
+		WithGitBranch("stars"),
+		WithGitMessage("{{.Stats.Total}} stars on {{.At.Format \"2006-01-02\"}}"),
+	)
+	require.NoError(err)
+	committed, err := committer.Commit([]string{outputDir}, testCommitData())
+	require.NoError(err)
+	require.True(committed)
+
+	// a removed file is committed as removed
+	require.NoError(os.Remove(filepath.Join(outputDir, "rust.md")))
+	committed, err = committer.Commit([]string{outputDir}, testCommitData())
+	require.NoError(err)
+	require.True(committed)
+	require.Equal("stars/go.md", runGit(require, dir, "ls-tree", "-r", "--name-only", "stars"))
+	require.Equal("2 stars on 2026-10-19", runGit(require, dir, "log", "-1", "--format=%s", "refs/heads/stars"))
+
+	// the checked out branch and the work tree are left alone
+	require.Equal("refs/heads/main", runGit(require, dir, "symbolic-ref", "HEAD"))
+	require.Equal("?? stars/", runGit(require, dir, "status", "--porcelain"))
+
+	require.NoError(committer.Push(remote))
+	require.Equal(runGit(require, dir, "rev-parse", "refs/heads/stars"), runGit(require, remote, "rev-parse", "refs/heads/stars"))
+}
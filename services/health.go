@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// GithubRepoURI looks a repository up by full name, GitHub redirects
+	// renamed repositories to their new name
+	GithubRepoURI = "https://api.github.com/repos/%s"
+
+	ErrorRepoStatus = "Unexpected status %d for %s"
+
+	HealthArchived = "archived"
+	HealthDisabled = "disabled"
+	HealthStale    = "stale"
+	HealthRenamed  = "renamed"
+	HealthMissing  = "missing"
+
+	// CleanupTemplate is the block of the packs listing the repositories
+	// which need cleaning up, it is empty unless CleanupSection is parsed
+	CleanupTemplate = "cleanup"
+	CleanupSection  = `{{define "cleanup"}}{{with unhealthy (repos .)}}
+## Needs cleanup
+
+{{range .}}- [{{.FullName}}]({{.URL}}) - {{.Detail}}
+{{end}}{{end}}{{end}}`
+)
+
+// healthOrder sorts the report, most severe first
+var healthOrder = map[string]int{
+	HealthMissing:  0,
+	HealthRenamed:  1,
+	HealthDisabled: 2,
+	HealthArchived: 3,
+	HealthStale:    4,
+}
+
+// HealthIssue is a starred repository which needs attention
+type HealthIssue struct {
+	FullName string `json:"full_name"`
+	URL      string `json:"url"`
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail"`
+}
+
+// CheckHealth flags disabled and archived repositories along with the ones
+// without a push for longer than staleAfter, a repository gets its most
+// severe issue only.
+func CheckHealth(userStarredRepositories UserStarredRepositories, staleAfter time.Duration) []HealthIssue {
+	staleBefore := now().Add(-staleAfter)
+	issues := make([]HealthIssue, 0)
+	for _, v := range userStarredRepositories {
+		issue := HealthIssue{FullName: v.FullName, URL: v.HTMLURL}
+		switch {
+		case v.Disabled:
+			issue.Kind, issue.Detail = HealthDisabled, "disabled by GitHub"
+		case v.Archived:
+			issue.Kind, issue.Detail = HealthArchived, "archived"
+		case !v.PushedAt.IsZero() && v.PushedAt.Before(staleBefore):
+			issue.Kind = HealthStale
+			issue.Detail = "no push since " + v.PushedAt.UTC().Format("2006-01-02")
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	SortHealthIssues(issues)
+	return issues
+}
+
+// Unhealthy is CheckHealth with DefaultStaleAfter, for templates
+func Unhealthy(userStarredRepositories UserStarredRepositories) []HealthIssue {
+	return CheckHealth(userStarredRepositories, DefaultStaleAfter)
+}
+
+func SortHealthIssues(issues []HealthIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Kind != issues[j].Kind {
+			return healthOrder[issues[i].Kind] < healthOrder[issues[j].Kind]
+		}
+		return strings.ToLower(issues[i].FullName) < strings.ToLower(issues[j].FullName)
+	})
+}
+
+// CheckGone looks up repositories which were starred, e.g. in a previous
+// output, but are not in current anymore. Renamed ones are followed through
+// GitHub's redirect, the node_id tells whether the new name is still
+// starred. Deleted and private ones answer 404. Repositories which are just
+// unstarred are not reported.
+func (self *GitHubFetcher) CheckGone(fullNames []string, current UserStarredRepositories) ([]HealthIssue, error) {
+	starred := make(map[string]bool, len(current))
+	nodeIDs := make(map[string]bool, len(current))
+	for _, v := range current {
+		starred[strings.ToLower(v.FullName)] = true
+		nodeIDs[v.NodeID] = true
+	}
+
+	issues := make([]HealthIssue, 0)
+	for _, fullName := range fullNames {
+		if starred[strings.ToLower(fullName)] {
+			continue
+		}
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf(GithubRepoURI, fullName), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
+		resp, err := self.H.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		observeResponse(resp)
+		var repository Repository
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			issues = append(issues, HealthIssue{
+				FullName: fullName,
+				URL:      "https://github.com/" + fullName,
+				Kind:     HealthMissing,
+				Detail:   "not found, deleted or made private",
+			})
+		case http.StatusOK:
+			err = json.NewDecoder(resp.Body).Decode(&repository)
+		default:
+			err = fmt.Errorf(ErrorRepoStatus, resp.StatusCode, fullName)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK || strings.EqualFold(repository.FullName, fullName) {
+			continue
+		}
+		detail := "renamed to " + repository.FullName
+		if nodeIDs[repository.NodeID] {
+			detail += ", still starred"
+		}
+		issues = append(issues, HealthIssue{
+			FullName: fullName,
+			URL:      repository.HTMLURL,
+			Kind:     HealthRenamed,
+			Detail:   detail,
+		})
+	}
+	return issues, nil
+}
+
+// WriteHealthTable prints the issues as an aligned terminal table
+func WriteHealthTable(wr io.Writer, issues []HealthIssue) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Kind\tRepository\tDetail")
+	for _, issue := range issues {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", issue.Kind, issue.FullName, issue.Detail)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	now = func() time.Time { return time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	repos := Repos(testStarredRows())
+	disabled := Repository{FullName: "a/disabled", HTMLURL: "https://github.com/a/disabled", Disabled: true, Archived: true}
+	fresh := Repository{FullName: "a/fresh", PushedAt: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)}
+	repos = append(repos, disabled, fresh)
+
+	require.Equal([]HealthIssue{
+		{FullName: "a/disabled", URL: "https://github.com/a/disabled", Kind: HealthDisabled, Detail: "disabled by GitHub"},
+		{FullName: "stefanwuthrich/cached-google-places", URL: "https://github.com/stefanwuthrich/cached-google-places", Kind: HealthArchived, Detail: "archived"},
+		{FullName: "victorspringer/http-cache", URL: "https://github.com/victorspringer/http-cache", Kind: HealthStale, Detail: "no push since 2021-03-02"},
+	}, CheckHealth(repos, DefaultStaleAfter))
+	require.Len(CheckHealth(repos, 10*DefaultStaleAfter), 2)
+}
+
+func TestCheckGone(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repos/a/deleted",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Not Found"}`))
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repos/a/old-name",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusMovedPermanently, "")
+			resp.Header.Set("Location", "https://api.github.com/repositories/42")
+			return resp, nil
+		})
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repositories/42",
+		httpmock.NewStringResponder(http.StatusOK, `{"node_id": "NODE42", "full_name": "a/new-name", "html_url": "https://github.com/a/new-name"}`))
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repos/a/unstarred",
+		httpmock.NewStringResponder(http.StatusOK, `{"node_id": "NODE7", "full_name": "a/unstarred"}`))
+
+	fetcher, err := NewGitHubFetcher(WithToken("TOKEN"), WithUserName("alphawong"))
+	require.NoError(err)
+	current := UserStarredRepositories{{NodeID: "NODE42", FullName: "a/new-name"}, {FullName: "a/kept"}}
+	issues, err := fetcher.CheckGone([]string{"a/deleted", "A/Kept", "a/old-name", "a/unstarred"}, current)
+	require.NoError(err)
+	require.Equal([]HealthIssue{
+		{FullName: "a/deleted", URL: "https://github.com/a/deleted", Kind: HealthMissing, Detail: "not found, deleted or made private"},
+		{FullName: "a/old-name", URL: "https://github.com/a/new-name", Kind: HealthRenamed, Detail: "renamed to a/new-name, still starred"},
+	}, issues)
+	require.Equal(4, httpmock.GetTotalCallCount())
+}
+
+func TestCheckGoneWithUnexpectedStatus(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodGet, "https://api.github.com/repos/a/b",
+		httpmock.NewStringResponder(http.StatusForbidden, ""))
+
+	fetcher, err := NewGitHubFetcher(WithToken("TOKEN"), WithUserName("alphawong"))
+	require.NoError(err)
+	_, err = fetcher.CheckGone([]string{"a/b"}, nil)
+	require.EqualError(err, "Unexpected status 403 for a/b")
+}
+
+func TestWriteHealthTable(t *testing.T) {
+	require := require.New(t)
+	var output strings.Builder
+	require.NoError(WriteHealthTable(&output, []HealthIssue{
+		{FullName: "a/b", Kind: HealthArchived, Detail: "archived"},
+	}))
+	require.Equal("Kind      Repository  Detail\narchived  a/b         archived\n", output.String())
+}
+
+func TestCleanupSection(t *testing.T) {
+	require := require.New(t)
+	tpl, err := template.New("layout").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}# Stars{{block "cleanup" .}}{{end}}{{end}}`)
+	require.NoError(err)
+
+	var output strings.Builder
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Equal("# Stars", output.String())
+
+	_, err = tpl.Parse(CleanupSection)
+	require.NoError(err)
+	output.Reset()
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Contains(output.String(), "## Needs cleanup\n\n- [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) - archived\n")
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	ErrorHistoryRun = "No run %d, the history has %d runs"
+
+	// historyRunKey sorts runs chronologically as bolt keys
+	historyRunKey = "2006-01-02T15:04:05.000000000Z"
+
+	TimelineStarred   = "starred"
+	TimelineUnstarred = "unstarred"
+	TimelineRenamed   = "renamed"
+)
+
+// historyRuns is the bucket holding one star set per run keyed by run time
+var historyRuns = []byte("runs")
+
+// HistoryStar is a repository of a recorded run, ID identifies it across
+// renames
+type HistoryStar struct {
+	ID        int       `json:"id"`
+	FullName  string    `json:"full_name"`
+	Language  string    `json:"language"`
+	StarredAt time.Time `json:"starred_at"`
+	// Group is the group of the repository under the GroupBy of the run
+	Group string `json:"group,omitempty"`
+}
+
+// HistoryRun is the star set seen by one run, GroupBy names the grouping of
+// GroupKeys the run rendered with. It is empty for the runs recorded before
+// it was, they were grouped by language.
+type HistoryRun struct {
+	At      time.Time     `json:"at"`
+	GroupBy string        `json:"group_by,omitempty"`
+	Stars   []HistoryStar `json:"stars"`
+}
+
+// HistoryDelta is what changed between two runs
+type HistoryDelta struct {
+	Added   []HistoryStar
+	Removed []HistoryStar
+	// Renamed holds the stars of the later run whose name changed
+	Renamed []HistoryStar
+}
+
+// TimelineEvent is a change of one repository at the time of a run
+type TimelineEvent struct {
+	At       time.Time
+	Event    string
+	FullName string
+}
+
+// History stores the star set of every run in a bolt database
+type History struct {
+	db *bolt.DB
+}
+
+func OpenHistory(historyPath string) (*History, error) {
+	db, err := bolt.Open(historyPath, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(historyRuns)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &History{db: db}, nil
+}
+
+func (self *History) Close() error {
+	return self.db.Close()
+}
+
+// Record saves the repositories as the star set of the run at at, grouped
+// by groupBy, one of GroupKeys
+func (self *History) Record(at time.Time, groupBy string, userStarredRepositories UserStarredRepositories) error {
+	run := HistoryRun{At: at.UTC(), GroupBy: groupBy, Stars: make([]HistoryStar, 0, len(userStarredRepositories))}
+	groupKey := GroupKeys[groupBy]
+	for _, v := range userStarredRepositories {
+		star := HistoryStar{
+			ID:        v.ID,
+			FullName:  v.FullName,
+			Language:  v.Language,
+			StarredAt: v.StarredAt,
+		}
+		if groupKey != nil {
+			star.Group = groupKey(v)
+		}
+		run.Stars = append(run.Stars, star)
+	}
+	sort.Slice(run.Stars, func(i, j int) bool {
+		return run.Stars[i].ID < run.Stars[j].ID
+	})
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyRuns).Put([]byte(run.At.Format(historyRunKey)), b)
+	})
+}
+
+// Runs returns every recorded run, oldest first
+func (self *History) Runs() ([]HistoryRun, error) {
+	var runs []HistoryRun
+	err := self.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyRuns).ForEach(func(k []byte, v []byte) error {
+			var run HistoryRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	return runs, err
+}
+
+// Snapshot is the full_name to group snapshot of a recorded run as grouped
+// by groupBy. The groups are empty, unknown, when the run was grouped
+// differently, so that comparing with it never reports moves.
+func (self HistoryRun) Snapshot(groupBy string) map[string]string {
+	recorded := self.GroupBy
+	if recorded == "" {
+		recorded = "language"
+	}
+	snapshot := make(map[string]string, len(self.Stars))
+	for _, star := range self.Stars {
+		switch {
+		case recorded != groupBy:
+			snapshot[star.FullName] = ""
+		case self.GroupBy == "":
+			snapshot[star.FullName] = LanguageKey(Repository{Language: star.Language})
+		default:
+			snapshot[star.FullName] = star.Group
+		}
+	}
+	return snapshot
+}
+
+// DiffRuns compares two runs by repository ID
+func DiffRuns(from HistoryRun, to HistoryRun) HistoryDelta {
+	before := make(map[int]HistoryStar, len(from.Stars))
+	for _, star := range from.Stars {
+		before[star.ID] = star
+	}
+	var delta HistoryDelta
+	for _, star := range to.Stars {
+		previous, ok := before[star.ID]
+		switch {
+		case !ok:
+			delta.Added = append(delta.Added, star)
+		case previous.FullName != star.FullName:
+			delta.Renamed = append(delta.Renamed, star)
+		}
+		delete(before, star.ID)
+	}
+	for _, star := range from.Stars {
+		if _, ok := before[star.ID]; ok {
+			delta.Removed = append(delta.Removed, star)
+		}
+	}
+	return delta
+}
+
+// Timeline follows the repositories ever named fullName through the runs
+func Timeline(runs []HistoryRun, fullName string) []TimelineEvent {
+	ids := make(map[int]bool)
+	for _, run := range runs {
+		for _, star := range run.Stars {
+			if strings.EqualFold(star.FullName, fullName) {
+				ids[star.ID] = true
+			}
+		}
+	}
+
+	var events []TimelineEvent
+	names := make(map[int]string)
+	for _, run := range runs {
+		present := make(map[int]bool)
+		for _, star := range run.Stars {
+			if !ids[star.ID] {
+				continue
+			}
+			present[star.ID] = true
+			name, starred := names[star.ID]
+			switch {
+			case !starred:
+				events = append(events, TimelineEvent{At: run.At, Event: TimelineStarred, FullName: star.FullName})
+			case name != star.FullName:
+				events = append(events, TimelineEvent{At: run.At, Event: TimelineRenamed, FullName: star.FullName})
+			}
+			names[star.ID] = star.FullName
+		}
+		gone := make([]int, 0)
+		for id := range names {
+			if !present[id] {
+				gone = append(gone, id)
+			}
+		}
+		sort.Ints(gone)
+		for _, id := range gone {
+			events = append(events, TimelineEvent{At: run.At, Event: TimelineUnstarred, FullName: names[id]})
+			delete(names, id)
+		}
+	}
+	return events
+}
+
+// LanguageShare is the percentage of the stars of every run in language
+func LanguageShare(runs []HistoryRun, language string) []float64 {
+	shares := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		count := 0
+		for _, star := range run.Stars {
+			if LanguageKey(Repository{Language: star.Language}) == language {
+				count++
+			}
+		}
+		share := 0.0
+		if len(run.Stars) > 0 {
+			share = float64(count) * 100 / float64(len(run.Stars))
+		}
+		shares = append(shares, share)
+	}
+	return shares
+}
+
+// WriteHistoryRuns lists the runs numbered from 1, the numbers taken by
+// the history command
+func WriteHistoryRuns(wr io.Writer, runs []HistoryRun) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Run\tAt\tStars")
+	for i, run := range runs {
+		fmt.Fprintf(tw, "%d\t%s\t%d\n", i+1, run.At.Format(time.RFC3339), len(run.Stars))
+	}
+	return tw.Flush()
+}
+
+func WriteHistoryDelta(wr io.Writer, delta HistoryDelta) error {
+	for _, section := range []struct {
+		Sign  string
+		Stars []HistoryStar
+	}{
+		{"+", delta.Added},
+		{"-", delta.Removed},
+		{"~", delta.Renamed},
+	} {
+		for _, star := range section.Stars {
+			if _, err := fmt.Fprintf(wr, "%s %s\n", section.Sign, star.FullName); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(wr, "%d added, %d removed, %d renamed\n", len(delta.Added), len(delta.Removed), len(delta.Renamed))
+	return err
+}
+
+func WriteLanguageShare(wr io.Writer, runs []HistoryRun, language string) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Run\tAt\t%s\n", language)
+	for i, share := range LanguageShare(runs, language) {
+		fmt.Fprintf(tw, "%d\t%s\t%.1f%%\n", i+1, runs[i].At.Format(time.RFC3339), share)
+	}
+	return tw.Flush()
+}
+
+func WriteTimeline(wr io.Writer, events []TimelineEvent) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	for _, event := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", event.At.Format(time.RFC3339), event.Event, event.FullName)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testHistoryRuns() []HistoryRun {
+	at := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	return []HistoryRun{
+		{At: at, Stars: []HistoryStar{
+			{ID: 1, FullName: "a/rust", Language: "Rust"},
+			{ID: 2, FullName: "a/go", Language: "Go"},
+		}},
+		{At: at.AddDate(0, 0, 1), Stars: []HistoryStar{
+			{ID: 1, FullName: "a/rust-renamed", Language: "Rust"},
+			{ID: 3, FullName: "a/new", Language: ""},
+		}},
+		{At: at.AddDate(0, 0, 2), Stars: []HistoryStar{
+			{ID: 2, FullName: "a/go", Language: "Go"},
+			{ID: 3, FullName: "a/new", Language: ""},
+		}},
+	}
+}
+
+func TestHistoryRecord(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	history, err := OpenHistory(filepath.Join(dir, "stars.db"))
+	require.NoError(err)
+	at := time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC)
+	repos := Repos(testStarredRows())
+	require.NoError(history.Record(at.Add(time.Hour), "language", repos[:1]))
+	require.NoError(history.Record(at, "owner", repos))
+	require.NoError(history.Close())
+
+	// reopened, the runs are sorted by time
+	history, err = OpenHistory(filepath.Join(dir, "stars.db"))
+	require.NoError(err)
+	defer history.Close()
+	runs, err := history.Runs()
+	require.NoError(err)
+	require.Len(runs, 2)
+	require.True(at.Equal(runs[0].At))
+	require.Equal("owner", runs[0].GroupBy)
+	require.Equal([]HistoryStar{
+		{ID: 73893472, FullName: "victorspringer/http-cache", Language: "Go", StarredAt: time.Date(2021, 3, 18, 9, 30, 0, 0, time.UTC), Group: "victorspringer"},
+		{ID: 334331282, FullName: "stefanwuthrich/cached-google-places", Language: "JavaScript", StarredAt: time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC), Group: "stefanwuthrich"},
+	}, runs[0].Stars)
+	require.Len(runs[1].Stars, 1)
+}
+
+func TestHistoryRunSnapshot(t *testing.T) {
+	require := require.New(t)
+	run := HistoryRun{GroupBy: "owner", Stars: []HistoryStar{
+		{ID: 1, FullName: "a/go", Language: "Go", Group: "a"},
+	}}
+	require.Equal(map[string]string{"a/go": "a"}, run.Snapshot("owner"))
+	require.Equal(map[string]string{"a/go": ""}, run.Snapshot("language"))
+	require.False(DiffSnapshots(run.Snapshot("language"), map[string]string{"a/go": "Go"}).Changed())
+
+	// recorded before the grouping was, by language
+	run = testHistoryRuns()[1]
+	require.Equal(map[string]string{"a/rust-renamed": "Rust", "a/new": Others}, run.Snapshot("language"))
+	require.Equal(map[string]string{"a/rust-renamed": "", "a/new": ""}, run.Snapshot("topic"))
+}
+
+func TestDiffRuns(t *testing.T) {
+	require := require.New(t)
+	runs := testHistoryRuns()
+	require.Equal(HistoryDelta{
+		Added:   []HistoryStar{{ID: 3, FullName: "a/new"}},
+		Removed: []HistoryStar{{ID: 2, FullName: "a/go", Language: "Go"}},
+		Renamed: []HistoryStar{{ID: 1, FullName: "a/rust-renamed", Language: "Rust"}},
+	}, DiffRuns(runs[0], runs[1]))
+	require.Equal(HistoryDelta{}, DiffRuns(runs[2], runs[2]))
+}
+
+func TestTimeline(t *testing.T) {
+	require := require.New(t)
+	runs := testHistoryRuns()
+	require.Equal([]TimelineEvent{
+		{At: runs[0].At, Event: TimelineStarred, FullName: "a/rust"},
+		{At: runs[1].At, Event: TimelineRenamed, FullName: "a/rust-renamed"},
+		{At: runs[2].At, Event: TimelineUnstarred, FullName: "a/rust-renamed"},
+	}, Timeline(runs, "A/Rust"))
+	require.Equal([]TimelineEvent{
+		{At: runs[0].At, Event: TimelineStarred, FullName: "a/go"},
+		{At: runs[1].At, Event: TimelineUnstarred, FullName: "a/go"},
+		{At: runs[2].At, Event: TimelineStarred, FullName: "a/go"},
+	}, Timeline(runs, "a/go"))
+	require.Empty(Timeline(runs, "a/never"))
+}
+
+func TestLanguageShare(t *testing.T) {
+	require := require.New(t)
+	runs := testHistoryRuns()
+	require.Equal([]float64{50, 50, 0}, LanguageShare(runs, "Rust"))
+	require.Equal([]float64{0, 50, 50}, LanguageShare(runs, Others))
+}
+
+func TestWriteHistoryDelta(t *testing.T) {
+	require := require.New(t)
+	runs := testHistoryRuns()
+	var output strings.Builder
+	require.NoError(WriteHistoryDelta(&output, DiffRuns(runs[0], runs[1])))
+	require.Equal("+ a/new\n- a/go\n~ a/rust-renamed\n1 added, 1 removed, 1 renamed\n", output.String())
+}
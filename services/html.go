@@ -0,0 +1,208 @@
+package services
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	ErrorOutputDir = "Missing OutputDir"
+
+	DefaultHTMLTitle = "Stars"
+	// HTMLGroupDir holds one page per group inside the output directory
+	HTMLGroupDir = "groups"
+)
+
+// htmlAssets are copied verbatim next to the pages so the site works
+// offline and without any CDN.
+var htmlAssets = []string{"style.css", "theme.js", "search.js"}
+
+//go:embed html
+var htmlFS embed.FS
+
+var htmlTemplate = template.Must(
+	template.New("site.html").Funcs(template.FuncMap(TemplateFuncs())).ParseFS(htmlFS, "html/site.html"),
+)
+
+// ensure interface implement is correct
+var _ Printer = (*HTMLPrinter)(nil)
+
+type HTMLPrinterOption func(*HTMLPrinter)
+
+func WithHTMLTitle(title string) HTMLPrinterOption {
+	return func(htmlPrinter *HTMLPrinter) {
+		htmlPrinter.Title = title
+	}
+}
+
+func WithHTMLOutputDir(outputDir string) HTMLPrinterOption {
+	return func(htmlPrinter *HTMLPrinter) {
+		htmlPrinter.OutputDir = outputDir
+	}
+}
+
+// HTMLPrinter writes a static site: index.html with client-side search,
+// one page per group and the css/js assets.
+type HTMLPrinter struct {
+	Title     string
+	OutputDir string
+}
+
+type htmlGroup struct {
+	Name  string
+	Slug  string
+	Count int
+	Repos UserStarredRepositories
+}
+
+type htmlSearchEntry struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Group       string `json:"group"`
+	Page        string `json:"page"`
+}
+
+type htmlPage struct {
+	Title  string
+	Page   string
+	Root   string
+	Total  int
+	Groups []htmlGroup
+	Group  htmlGroup
+	Search []htmlSearchEntry
+}
+
+func NewHTMLPrinter(setters ...HTMLPrinterOption) (*HTMLPrinter, error) {
+	htmlPrinter := &HTMLPrinter{
+		Title:     DefaultHTMLTitle,
+		OutputDir: "",
+	}
+
+	for _, setter := range setters {
+		setter(htmlPrinter)
+	}
+
+	if htmlPrinter.OutputDir == "" {
+		return nil, errors.New(ErrorOutputDir)
+	}
+
+	return htmlPrinter, nil
+}
+
+func (self *HTMLPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	groups := htmlGroups(markDownRows)
+	if err := os.MkdirAll(filepath.Join(self.OutputDir, HTMLGroupDir), 0755); err != nil {
+		return err
+	}
+
+	for _, asset := range htmlAssets {
+		b, err := htmlFS.ReadFile("html/" + asset)
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(self.OutputDir, asset), func(wr io.Writer) error {
+			_, err := wr.Write(b)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	err := writeOutput(filepath.Join(self.OutputDir, "index.html"), func(wr io.Writer) error {
+		return self.printIndex(wr, groups)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		err := writeOutput(filepath.Join(self.OutputDir, HTMLGroupDir, group.Slug+".html"), func(wr io.Writer) error {
+			return self.printGroup(wr, group)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderFile renders the file of the site at name, e.g. "index.html" or
+// "groups/go.html", into memory. It returns os.ErrNotExist for any other
+// name.
+func (self *HTMLPrinter) RenderFile(markDownRows []MarkDownRow, name string) ([]byte, error) {
+	for _, asset := range htmlAssets {
+		if name == asset {
+			return htmlFS.ReadFile("html/" + asset)
+		}
+	}
+	groups := htmlGroups(markDownRows)
+	if name == "index.html" {
+		return renderBytes(func(wr io.Writer) error {
+			return self.printIndex(wr, groups)
+		})
+	}
+	for _, group := range groups {
+		if name == path.Join(HTMLGroupDir, group.Slug+".html") {
+			return renderBytes(func(wr io.Writer) error {
+				return self.printGroup(wr, group)
+			})
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (self *HTMLPrinter) printIndex(wr io.Writer, groups []htmlGroup) error {
+	page := htmlPage{
+		Title:  self.Title,
+		Page:   "Index",
+		Root:   "",
+		Groups: groups,
+		Search: []htmlSearchEntry{},
+	}
+	for _, group := range groups {
+		page.Total += group.Count
+		for _, v := range group.Repos {
+			page.Search = append(page.Search, htmlSearchEntry{
+				Name:        v.FullName,
+				URL:         v.HTMLURL,
+				Description: v.Description,
+				Group:       group.Name,
+				Page:        group.Slug,
+			})
+		}
+	}
+	return htmlTemplate.ExecuteTemplate(wr, "index", page)
+}
+
+func (self *HTMLPrinter) printGroup(wr io.Writer, group htmlGroup) error {
+	return htmlTemplate.ExecuteTemplate(wr, "group", htmlPage{
+		Title: self.Title,
+		Page:  group.Name,
+		Root:  "../",
+		Group: group,
+	})
+}
+
+func htmlGroups(markDownRows []MarkDownRow) []htmlGroup {
+	names := make([]string, 0, len(markDownRows))
+	for _, row := range markDownRows {
+		names = append(names, row.Language)
+	}
+	slugs := UniqueSlugs(names)
+	groups := make([]htmlGroup, 0, len(markDownRows))
+	for i, row := range markDownRows {
+		groups = append(groups, htmlGroup{
+			Name:  row.Language,
+			Slug:  slugs[i],
+			Count: len(row.Repos),
+			Repos: row.Repos,
+		})
+	}
+	return groups
+}
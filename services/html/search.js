@@ -0,0 +1,54 @@
+(function () {
+  var index = JSON.parse(document.getElementById("search-index").textContent);
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var groups = document.getElementById("groups");
+  var limit = 200;
+
+  function matches(entry, terms) {
+    var text = (entry.name + " " + entry.description + " " + entry.group).toLowerCase();
+    for (var i = 0; i < terms.length; i++) {
+      if (text.indexOf(terms[i]) === -1) {
+        return false;
+      }
+    }
+    return true;
+  }
+
+  function render() {
+    var query = input.value.trim().toLowerCase();
+    results.textContent = "";
+    if (query === "") {
+      results.hidden = true;
+      groups.hidden = false;
+      return;
+    }
+    var terms = query.split(/\s+/);
+    var shown = 0;
+    for (var i = 0; i < index.length && shown < limit; i++) {
+      var entry = index[i];
+      if (!matches(entry, terms)) {
+        continue;
+      }
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.href = entry.url;
+      link.textContent = entry.name;
+      var meta = document.createElement("a");
+      meta.className = "meta";
+      meta.href = "groups/" + entry.page + ".html";
+      meta.textContent = " · " + entry.group;
+      var description = document.createElement("div");
+      description.textContent = entry.description;
+      item.appendChild(link);
+      item.appendChild(meta);
+      item.appendChild(description);
+      results.appendChild(item);
+      shown++;
+    }
+    results.hidden = false;
+    groups.hidden = true;
+  }
+
+  input.addEventListener("input", render);
+})();
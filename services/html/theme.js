@@ -0,0 +1,18 @@
+(function () {
+  var root = document.documentElement;
+  var saved = localStorage.getItem("stars-theme");
+  if (saved) {
+    root.setAttribute("data-theme", saved);
+  }
+  document.addEventListener("DOMContentLoaded", function () {
+    document.getElementById("theme-toggle").addEventListener("click", function () {
+      var current = root.getAttribute("data-theme");
+      if (!current) {
+        current = window.matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
+      }
+      var next = current === "dark" ? "light" : "dark";
+      root.setAttribute("data-theme", next);
+      localStorage.setItem("stars-theme", next);
+    });
+  });
+})();
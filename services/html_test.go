@@ -0,0 +1,77 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTMLPrinterWithEmptyOutputDir(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewHTMLPrinter()
+	require.EqualError(err, ErrorOutputDir)
+	require.Nil(printer)
+}
+
+func TestHTMLPrinterPrintSlice(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "site")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewHTMLPrinter(
+		WithHTMLTitle("My <Stars>"),
+		WithHTMLOutputDir(dir),
+	)
+	require.NoError(err)
+	err = printer.PrintSlice(testStarredRows())
+	require.NoError(err)
+
+	for _, name := range []string{"index.html", "style.css", "theme.js", "search.js", "groups/go.html", "groups/javascript.html"} {
+		require.FileExists(filepath.Join(dir, name))
+	}
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(err)
+	require.Contains(string(index), "<title>Index · My &lt;Stars&gt;</title>")
+	require.Contains(string(index), `<a href="groups/javascript.html">JavaScript</a>`)
+	require.Contains(string(index), `"name":"victorspringer/http-cache"`)
+	// the search index must not be able to close the script element
+	require.Contains(string(index), `\u003cresults\u003e`)
+	require.NotContains(string(index), "<results>")
+
+	group, err := ioutil.ReadFile(filepath.Join(dir, "groups", "javascript.html"))
+	require.NoError(err)
+	require.Contains(string(group), `<link rel="stylesheet" href="../style.css">`)
+	require.Contains(string(group), `<tr class="archived"><td><a href="https://github.com/stefanwuthrich/cached-google-places">stefanwuthrich/cached-google-places</a></td><td>Caches &#34;Google Places&#34; &lt;results&gt;, &amp; more</td><td>1</td><td>2021-03-19</td></tr>`)
+
+	for _, page := range [][]byte{index, group} {
+		require.NotContains(string(page), `src="http`)
+		require.NotContains(string(page), `stylesheet" href="http`)
+	}
+}
+
+func TestHTMLPrinterRenderFile(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "site")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewHTMLPrinter(WithHTMLOutputDir(dir))
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	for _, name := range []string{"index.html", "style.css", "groups/go.html"} {
+		expected, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(err)
+		actual, err := printer.RenderFile(testStarredRows(), name)
+		require.NoError(err)
+		require.Equal(string(expected), string(actual), name)
+	}
+
+	_, err = printer.RenderFile(testStarredRows(), "groups/rust.html")
+	require.True(os.IsNotExist(err))
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	ErrorInjectTarget       = "Missing inject TargetPath"
+	ErrorMissingMarkers     = "%s: no <!-- STARS:START --> marker found"
+	ErrorMissingEndMarker   = "%s:%d: %s has no matching %s"
+	ErrorUnexpectedMarker   = "%s:%d: unexpected %s"
+	ErrorInjectRegion       = "%s:%d: %s: %w"
+	ErrorMissingRegionBlock = "template has no %q block"
+
+	// InjectDefaultRegion is the template rendered into an unnamed region
+	InjectDefaultRegion = "layout"
+)
+
+// injectMarker matches <!-- STARS:START -->, <!-- STARS:END --> and their
+// named forms such as <!-- STARS:START toc -->.
+var injectMarker = regexp.MustCompile(`<!--\s*STARS:(START|END)(?:\s+([\w.-]+))?\s*-->`)
+
+// ensure interface implement is correct
+var _ Renderer = (*InjectPrinter)(nil)
+
+type InjectPrinterOption func(*InjectPrinter)
+
+// WithInjectTemplate takes the results of template.ParseFiles, a parse
+// error is returned by NewInjectPrinter
+func WithInjectTemplate(t *template.Template, err error) InjectPrinterOption {
+	return func(injectPrinter *InjectPrinter) {
+		injectPrinter.BaseTemplate = t
+		injectPrinter.err = err
+	}
+}
+
+func WithInjectTargetPath(targetPath string) InjectPrinterOption {
+	return func(injectPrinter *InjectPrinter) {
+		injectPrinter.TargetPath = targetPath
+	}
+}
+
+// InjectPrinter rewrites only the marked regions of an existing file, every
+// region is rendered with the template block of the same name.
+type InjectPrinter struct {
+	BaseTemplate *template.Template
+	TargetPath   string
+	err          error
+}
+
+func NewInjectPrinter(setters ...InjectPrinterOption) (*InjectPrinter, error) {
+	injectPrinter := &InjectPrinter{
+		BaseTemplate: nil,
+		TargetPath:   "",
+	}
+
+	for _, setter := range setters {
+		setter(injectPrinter)
+	}
+
+	if injectPrinter.err != nil {
+		return nil, injectPrinter.err
+	}
+
+	if injectPrinter.BaseTemplate == nil {
+		return nil, errors.New(ErrorBaseTemplate)
+	}
+
+	if injectPrinter.TargetPath == "" {
+		return nil, errors.New(ErrorInjectTarget)
+	}
+
+	return injectPrinter, nil
+}
+
+func (self *InjectPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	injected, err := self.Render(markDownRows)
+	if err != nil {
+		return err
+	}
+	return writeOutput(self.TargetPath, func(wr io.Writer) error {
+		_, err := wr.Write(injected)
+		return err
+	})
+}
+
+// Render returns the target file with its regions injected
+func (self *InjectPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	document, err := ioutil.ReadFile(self.TargetPath)
+	if err != nil {
+		return nil, err
+	}
+	return self.Inject(document, markDownRows)
+}
+
+// Inject returns document with every marked region replaced by its rendered
+// template block, every byte outside the regions is kept as is.
+func (self *InjectPrinter) Inject(document []byte, markDownRows []MarkDownRow) ([]byte, error) {
+	return InjectRegions(self.TargetPath, document, func(region string) (string, error) {
+		if self.BaseTemplate.Lookup(region) == nil {
+			return "", fmt.Errorf(ErrorMissingRegionBlock, region)
+		}
+		var output strings.Builder
+		err := self.BaseTemplate.ExecuteTemplate(&output, region, markDownRows)
+		return output.String(), err
+	})
+}
+
+// InjectRegions replaces the content between each pair of START and END
+// markers with render(region), where an unnamed region renders
+// InjectDefaultRegion. name is only used in error messages.
+func InjectRegions(
+	name string,
+	document []byte,
+	render func(region string) (string, error),
+) ([]byte, error) {
+	markers := injectMarker.FindAllSubmatchIndex(document, -1)
+	var output bytes.Buffer
+	last := 0
+	regions := 0
+	for i := 0; i < len(markers); i++ {
+		start := markers[i]
+		kind, region := markerParts(document, start)
+		if kind != "START" {
+			return nil, fmt.Errorf(ErrorUnexpectedMarker, name, lineOf(document, start[0]), markerName(kind, region))
+		}
+		if i+1 >= len(markers) {
+			return nil, fmt.Errorf(ErrorMissingEndMarker, name, lineOf(document, start[0]), markerName(kind, region), markerName("END", region))
+		}
+		end := markers[i+1]
+		endKind, endRegion := markerParts(document, end)
+		if endKind != "END" || endRegion != region {
+			return nil, fmt.Errorf(ErrorMissingEndMarker, name, lineOf(document, start[0]), markerName(kind, region), markerName("END", region))
+		}
+
+		templateName := region
+		if templateName == "" {
+			templateName = InjectDefaultRegion
+		}
+		content, err := render(templateName)
+		if err != nil {
+			return nil, fmt.Errorf(ErrorInjectRegion, name, lineOf(document, start[0]), markerName(kind, region), err)
+		}
+		if !strings.HasSuffix(content, "\n") {
+			content = content + "\n"
+		}
+
+		output.Write(document[last:start[1]])
+		output.WriteString("\n")
+		output.WriteString(content)
+		last = end[0]
+		regions++
+		i++
+	}
+	if regions == 0 {
+		return nil, fmt.Errorf(ErrorMissingMarkers, name)
+	}
+	output.Write(document[last:])
+	return output.Bytes(), nil
+}
+
+func markerParts(document []byte, match []int) (kind string, region string) {
+	kind = string(document[match[2]:match[3]])
+	if match[4] >= 0 {
+		region = string(document[match[4]:match[5]])
+	}
+	return
+}
+
+func markerName(kind string, region string) string {
+	if region == "" {
+		return fmt.Sprintf("<!-- STARS:%s -->", kind)
+	}
+	return fmt.Sprintf("<!-- STARS:%s %s -->", kind, region)
+}
+
+func lineOf(document []byte, offset int) int {
+	return bytes.Count(document[:offset], []byte("\n")) + 1
+}
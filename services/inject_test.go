@@ -0,0 +1,113 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+var injectTestTemplate = template.Must(template.New("layout").Parse(
+	`{{define "layout"}}Language|⭐️
+---|---
+{{range .}}{{.Language}}|{{.Stars}}
+{{end}}{{end}}{{define "count"}}{{len .}} groups{{end}}`,
+))
+
+func TestNewInjectPrinterWithEmptyTargetPath(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewInjectPrinter(
+		WithInjectTemplate(injectTestTemplate, nil),
+	)
+	require.EqualError(err, ErrorInjectTarget)
+	require.Nil(printer)
+}
+
+func TestInjectPrinterInject(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewInjectPrinter(
+		WithInjectTemplate(injectTestTemplate, nil),
+		WithInjectTargetPath("README.md"),
+	)
+	require.NoError(err)
+
+	document := "# Hand written\r\nkeep  me\n<!-- STARS:START count -->old<!-- STARS:END count -->\n\n" +
+		"<!-- STARS:START -->\nold table\n<!-- STARS:END -->\ntrailing text without newline"
+	actual, err := printer.Inject([]byte(document), testStarredRows())
+	require.NoError(err)
+	expected := "# Hand written\r\nkeep  me\n<!-- STARS:START count -->\n2 groups\n<!-- STARS:END count -->\n\n" +
+		"<!-- STARS:START -->\nLanguage|⭐️\n---|---\nGo|1\nJavaScript|1\n<!-- STARS:END -->\ntrailing text without newline"
+	require.Equal(expected, string(actual))
+
+	again, err := printer.Inject(actual, testStarredRows())
+	require.NoError(err)
+	require.Equal(expected, string(again))
+}
+
+func TestInjectPrinterInjectErrors(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewInjectPrinter(
+		WithInjectTemplate(injectTestTemplate, nil),
+		WithInjectTargetPath("README.md"),
+	)
+	require.NoError(err)
+
+	_, err = printer.Inject([]byte("# no markers\n"), nil)
+	require.EqualError(err, "README.md: no <!-- STARS:START --> marker found")
+
+	_, err = printer.Inject([]byte("a\n<!-- STARS:START -->\n"), nil)
+	require.EqualError(err, "README.md:2: <!-- STARS:START --> has no matching <!-- STARS:END -->")
+
+	_, err = printer.Inject([]byte("<!-- STARS:START toc -->\n<!-- STARS:END -->\n"), nil)
+	require.EqualError(err, "README.md:1: <!-- STARS:START toc --> has no matching <!-- STARS:END toc -->")
+
+	_, err = printer.Inject([]byte("\n\n<!-- STARS:END -->\n"), nil)
+	require.EqualError(err, "README.md:3: unexpected <!-- STARS:END -->")
+
+	_, err = printer.Inject([]byte("<!-- STARS:START toc --><!-- STARS:END toc -->"), nil)
+	require.EqualError(err, `README.md:1: <!-- STARS:START toc -->: template has no "toc" block`)
+}
+
+func TestInjectPrinterPrintSlice(t *testing.T) {
+	require := require.New(t)
+	tmpfile, err := ioutil.TempFile("", "README.*.md")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("intro\n<!-- STARS:START count -->\n<!-- STARS:END count -->\noutro\n")
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+
+	printer, err := NewInjectPrinter(
+		WithInjectTemplate(injectTestTemplate, nil),
+		WithInjectTargetPath(tmpfile.Name()),
+	)
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	actual, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(err)
+	require.Equal("intro\n<!-- STARS:START count -->\n2 groups\n<!-- STARS:END count -->\noutro\n", string(actual))
+}
+
+func TestInjectPrinterPrintSliceKeepsFileOnError(t *testing.T) {
+	require := require.New(t)
+	tmpfile, err := ioutil.TempFile("", "README.*.md")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("no markers\n")
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+
+	printer, err := NewInjectPrinter(
+		WithInjectTemplate(injectTestTemplate, nil),
+		WithInjectTargetPath(tmpfile.Name()),
+	)
+	require.NoError(err)
+	require.Error(printer.PrintSlice(testStarredRows()))
+
+	actual, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(err)
+	require.Equal("no markers\n", string(actual))
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"sort"
+)
+
+// ensure interface implement is correct
+var _ ListStarrer = (*GitHubStarrer)(nil)
+
+const (
+	// userListsQuery pages through the star lists of a user along with the
+	// first page of their repositories
+	userListsQuery = `query($login: String!, $cursor: String) { user(login: $login) { lists(first: 100, after: $cursor) { pageInfo { hasNextPage endCursor } nodes { id name items(first: 100) { pageInfo { hasNextPage endCursor } nodes { ... on Repository { nameWithOwner } } } } } } }`
+	// listItemsQuery pages through the repositories of a star list
+	listItemsQuery = `query($id: ID!, $cursor: String) { node(id: $id) { ... on UserList { items(first: 100, after: $cursor) { pageInfo { hasNextPage endCursor } nodes { ... on Repository { nameWithOwner } } } } } }`
+	// viewerListsQuery pages through the star lists of the owner of the token
+	viewerListsQuery   = `query($cursor: String) { viewer { lists(first: 100, after: $cursor) { pageInfo { hasNextPage endCursor } nodes { id name } } } }`
+	createListMutation = `mutation($name: String!) { createUserList(input: {name: $name}) { list { id } } }`
+	// updateListsMutation replaces the star lists a repository is in
+	updateListsMutation = `mutation($item: ID!, $lists: [ID!]!) { updateUserListsForItem(input: {itemId: $item, listIds: $lists}) { clientMutationId } }`
+)
+
+// ListStarrer is implemented by the starrers which can put a starred
+// repository back into star lists
+type ListStarrer interface {
+	SetStarLists(nodeID string, lists []string) error
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphQLListItems struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"nodes"`
+}
+
+type graphQLLists struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		ID    string           `json:"id"`
+		Name  string           `json:"name"`
+		Items graphQLListItems `json:"items"`
+	} `json:"nodes"`
+}
+
+// StarLists returns the names of the star lists of userName, sorted, by
+// the owner/repo of the repositories in them
+func (self *GitHubStarrer) StarLists(userName string) (map[string][]string, error) {
+	starLists := make(map[string][]string)
+	variables := map[string]interface{}{"login": userName, "cursor": nil}
+	for {
+		var data struct {
+			User *struct {
+				Lists graphQLLists `json:"lists"`
+			} `json:"user"`
+		}
+		if err := self.graphQL(userListsQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		if data.User == nil {
+			return nil, ErrNotFound
+		}
+		for _, list := range data.User.Lists.Nodes {
+			items := list.Items
+			for {
+				for _, item := range items.Nodes {
+					// the items which are not repositories have no name
+					if item.NameWithOwner != "" {
+						starLists[item.NameWithOwner] = append(starLists[item.NameWithOwner], list.Name)
+					}
+				}
+				if !items.PageInfo.HasNextPage {
+					break
+				}
+				next, err := self.listItems(list.ID, items.PageInfo.EndCursor)
+				if err != nil {
+					return nil, err
+				}
+				items = next
+			}
+		}
+		if !data.User.Lists.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = data.User.Lists.PageInfo.EndCursor
+	}
+	for _, lists := range starLists {
+		sort.Strings(lists)
+	}
+	return starLists, nil
+}
+
+func (self *GitHubStarrer) listItems(listID string, cursor string) (graphQLListItems, error) {
+	var data struct {
+		Node struct {
+			Items graphQLListItems `json:"items"`
+		} `json:"node"`
+	}
+	err := self.graphQL(listItemsQuery, map[string]interface{}{"id": listID, "cursor": cursor}, &data)
+	return data.Node.Items, err
+}
+
+// SetStarLists puts the repository with nodeID into the star lists of the
+// owner of Token named lists, and out of the other ones. The lists missing
+// are created.
+func (self *GitHubStarrer) SetStarLists(nodeID string, lists []string) error {
+	if self.lists == nil {
+		if err := self.loadLists(); err != nil {
+			return err
+		}
+	}
+	listIDs := make([]string, 0, len(lists))
+	for _, name := range lists {
+		listID, ok := self.lists[name]
+		if !ok {
+			var data struct {
+				CreateUserList struct {
+					List struct {
+						ID string `json:"id"`
+					} `json:"list"`
+				} `json:"createUserList"`
+			}
+			if err := self.graphQL(createListMutation, map[string]interface{}{"name": name}, &data); err != nil {
+				return err
+			}
+			listID = data.CreateUserList.List.ID
+			self.lists[name] = listID
+		}
+		listIDs = append(listIDs, listID)
+	}
+	return self.graphQL(updateListsMutation, map[string]interface{}{"item": nodeID, "lists": listIDs}, nil)
+}
+
+// loadLists reads the ids of the star lists of the owner of Token by name
+func (self *GitHubStarrer) loadLists() error {
+	lists := make(map[string]string)
+	variables := map[string]interface{}{"cursor": nil}
+	for {
+		var data struct {
+			Viewer struct {
+				Lists graphQLLists `json:"lists"`
+			} `json:"viewer"`
+		}
+		if err := self.graphQL(viewerListsQuery, variables, &data); err != nil {
+			return err
+		}
+		for _, list := range data.Viewer.Lists.Nodes {
+			lists[list.Name] = list.ID
+		}
+		if !data.Viewer.Lists.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = data.Viewer.Lists.PageInfo.EndCursor
+	}
+	self.lists = lists
+	return nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func TestGitHubStarrerStarLists(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodPost, GithubGraphQLURI,
+		func(req *http.Request) (*http.Response, error) {
+			var body graphQLRequest
+			require.NoError(json.NewDecoder(req.Body).Decode(&body))
+			switch {
+			case body.Query == userListsQuery && body.Variables["cursor"] == nil:
+				require.Equal("alphawong", body.Variables["login"])
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"user": {"lists": {
+					"pageInfo": {"hasNextPage": true, "endCursor": "LISTS1"},
+					"nodes": [{"id": "LIST1", "name": "web", "items": {
+						"pageInfo": {"hasNextPage": true, "endCursor": "ITEMS1"},
+						"nodes": [{"nameWithOwner": "a/one"}, {}]
+					}}]
+				}}}}`), nil
+			case body.Query == userListsQuery:
+				require.Equal("LISTS1", body.Variables["cursor"])
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"user": {"lists": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"id": "LIST2", "name": "go", "items": {
+						"pageInfo": {"hasNextPage": false},
+						"nodes": [{"nameWithOwner": "a/two"}]
+					}}]
+				}}}}`), nil
+			case body.Query == listItemsQuery:
+				require.Equal("LIST1", body.Variables["id"])
+				require.Equal("ITEMS1", body.Variables["cursor"])
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"node": {"items": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"nameWithOwner": "a/two"}]
+				}}}}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusBadRequest, ""), nil
+		})
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	starLists, err := starrer.StarLists("alphawong")
+	require.NoError(err)
+	require.Equal(map[string][]string{
+		"a/one": {"web"},
+		"a/two": {"go", "web"},
+	}, starLists)
+}
+
+func TestGitHubStarrerStarListsWithErrors(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodPost, GithubGraphQLURI,
+		httpmock.NewStringResponder(http.StatusOK, `{"data": null, "errors": [{"message": "Your token has not been granted the required scopes"}]}`))
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	_, err = starrer.StarLists("alphawong")
+	require.EqualError(err, "GraphQL error: Your token has not been granted the required scopes")
+}
+
+func TestGitHubStarrerSetStarLists(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var created []string
+	var updated []interface{}
+	httpmock.RegisterResponder(http.MethodPost, GithubGraphQLURI,
+		func(req *http.Request) (*http.Response, error) {
+			var body graphQLRequest
+			require.NoError(json.NewDecoder(req.Body).Decode(&body))
+			switch body.Query {
+			case viewerListsQuery:
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"viewer": {"lists": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"id": "LIST1", "name": "web"}]
+				}}}}`), nil
+			case createListMutation:
+				name := body.Variables["name"].(string)
+				created = append(created, name)
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"createUserList": {"list": {"id": "NEW-`+strings.ToUpper(name)+`"}}}}`), nil
+			case updateListsMutation:
+				updated = append(updated, body.Variables["item"], body.Variables["lists"])
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"updateUserListsForItem": {"clientMutationId": null}}}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusBadRequest, ""), nil
+		})
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	require.NoError(starrer.SetStarLists("NODE1", []string{"go", "web"}))
+	require.NoError(starrer.SetStarLists("NODE2", []string{"go"}))
+	// the lists are read once and go is only created once
+	require.Equal([]string{"go"}, created)
+	require.Equal(1, httpmock.GetCallCountInfo()["POST "+GithubGraphQLURI]-len(created)-len(updated)/2)
+	require.Equal([]interface{}{
+		"NODE1", []interface{}{"NEW-GO", "LIST1"},
+		"NODE2", []interface{}{"NEW-GO"},
+	}, updated)
+}
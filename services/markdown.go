@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"text/template"
+)
+
+const (
+	DefaultMarkdownTitle = "Stars"
+	// MarkdownContents is the heading of the table of contents, templates
+	// write it with {{contents}} so that it matches the anchors
+	MarkdownContents = "Contents"
+)
+
+// markdownTemplate renders a table of contents followed by one anchored
+// section per group, the group name stays alone on its heading line so the
+// diff command can still tell which group a repository belongs to.
+var markdownTemplate = template.Must(template.New("markdown").Funcs(TemplateFuncs()).Parse(`# {{.Title}}
+
+## {{contents}}
+
+{{range .Sections}}- [{{.Language}}](#{{.Anchor}}) ({{len .Repos}})
+{{end}}{{range .Sections}}
+## {{.Language}}
+
+{{len .Repos}} {{pluralize (len .Repos) "repository" "repositories"}}
+
+{{if .Collapse}}<details>
+<summary>Show {{len .Repos}} {{pluralize (len .Repos) "repository" "repositories"}}</summary>
+
+{{end}}{{range .Repos}}- [{{.FullName}}]({{.HTMLURL}}){{if .Description}} - {{mdescape .Description}}{{end}}
+{{end}}{{if .Collapse}}
+</details>
+{{end}}
+[⬆ Back to top](#{{$.TopAnchor}})
+{{end}}`))
+
+// ensure interface implement is correct
+var _ Renderer = (*MarkdownPrinter)(nil)
+
+type MarkdownPrinterOption func(*MarkdownPrinter)
+
+func WithMarkdownTitle(title string) MarkdownPrinterOption {
+	return func(markdownPrinter *MarkdownPrinter) {
+		markdownPrinter.Title = title
+	}
+}
+
+// WithMarkdownCollapse folds groups holding more than n repositories into a
+// <details> block, 0 never folds
+func WithMarkdownCollapse(n int) MarkdownPrinterOption {
+	return func(markdownPrinter *MarkdownPrinter) {
+		markdownPrinter.Collapse = n
+	}
+}
+
+func WithMarkdownOutputPath(outputPath string) MarkdownPrinterOption {
+	return func(markdownPrinter *MarkdownPrinter) {
+		markdownPrinter.OutputPath = outputPath
+	}
+}
+
+type MarkdownPrinter struct {
+	Title      string
+	Collapse   int
+	OutputPath string
+}
+
+// markdownSection is a group along with the anchor of its heading
+type markdownSection struct {
+	MarkDownRow
+	Anchor   string
+	Collapse bool
+}
+
+func NewMarkdownPrinter(setters ...MarkdownPrinterOption) (*MarkdownPrinter, error) {
+	markdownPrinter := &MarkdownPrinter{
+		Title:      DefaultMarkdownTitle,
+		Collapse:   0,
+		OutputPath: "",
+	}
+
+	for _, setter := range setters {
+		setter(markdownPrinter)
+	}
+
+	if markdownPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return markdownPrinter, nil
+}
+
+func (self *MarkdownPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *MarkdownPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *MarkdownPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	// the title and contents headings come first on the page, a group
+	// sharing their name gets the next free anchor
+	headings := []string{self.Title, MarkdownContents}
+	for _, markDownRow := range markDownRows {
+		headings = append(headings, markDownRow.Language)
+	}
+	anchors := GitHubAnchors(headings)
+
+	sections := make([]markdownSection, 0, len(markDownRows))
+	for i, markDownRow := range markDownRows {
+		sections = append(sections, markdownSection{
+			MarkDownRow: markDownRow,
+			Anchor:      anchors[i+2],
+			Collapse:    self.Collapse > 0 && len(markDownRow.Repos) > self.Collapse,
+		})
+	}
+	return markdownTemplate.Execute(wr, struct {
+		Title     string
+		TopAnchor string
+		Sections  []markdownSection
+	}{
+		Title:     self.Title,
+		TopAnchor: anchors[0],
+		Sections:  sections,
+	})
+}
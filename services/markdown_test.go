@@ -0,0 +1,70 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMarkdownPrinterWithEmptyOutputPath(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewMarkdownPrinter()
+	require.EqualError(err, ErrorOutputPath)
+	require.Nil(printer)
+}
+
+func TestMarkdownPrinterPrint(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewMarkdownPrinter(
+		WithMarkdownOutputPath("stars.md"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	requireGolden(t, "markdown.golden.md", output.String())
+
+	snapshot := ParseOutputSnapshot([]byte(output.String()))
+	require.Equal("Go", snapshot["victorspringer/http-cache"])
+	require.Equal("JavaScript", snapshot["stefanwuthrich/cached-google-places"])
+}
+
+func TestMarkdownPrinterPrintWithCollapse(t *testing.T) {
+	require := require.New(t)
+	markDownRows := testStarredRows()
+	markDownRows[0].Repos = append(markDownRows[0].Repos, markDownRows[1].Repos...)
+
+	printer, err := NewMarkdownPrinter(
+		WithMarkdownCollapse(1),
+		WithMarkdownOutputPath("stars.md"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, markDownRows)
+	require.NoError(err)
+	require.Equal(1, strings.Count(output.String(), "<details>"))
+	require.Contains(output.String(), "<summary>Show 2 repositories</summary>")
+}
+
+func TestMarkdownPrinterPrintWithDuplicateAnchors(t *testing.T) {
+	require := require.New(t)
+	markDownRows := []MarkDownRow{
+		{Language: "C"},
+		{Language: "C++"},
+		{Language: "Stars"},
+	}
+
+	printer, err := NewMarkdownPrinter(
+		WithMarkdownOutputPath("stars.md"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, markDownRows)
+	require.NoError(err)
+	require.Contains(output.String(), "- [C](#c) (0)\n- [C++](#c-1) (0)\n- [Stars](#stars-1) (0)\n")
+	require.Equal(3, strings.Count(output.String(), "[⬆ Back to top](#stars)"))
+}
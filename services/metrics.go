@@ -0,0 +1,289 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetricsContentType is the content type of the Prometheus text format
+	MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	MetricRequests = "stars_github_requests_total"
+	// MetricStarRetries only counts the star and unstar requests, fetches
+	// are not retried but fail the run
+	MetricStarRetries    = "stars_star_retries_total"
+	MetricRateLimit      = "stars_github_rate_limit_remaining"
+	MetricPages          = "stars_github_pages_fetched_total"
+	MetricFetchDuration  = "stars_fetch_duration_seconds"
+	MetricRenderDuration = "stars_render_duration_seconds"
+	MetricGroupStars     = "stars_group_stars"
+	MetricLastSuccess    = "stars_last_success_timestamp_seconds"
+	MetricRunFailures    = "stars_run_failures_total"
+
+	ErrorMetricUndeclared = "Undeclared metric %q"
+
+	metricCounter   = "counter"
+	metricGauge     = "gauge"
+	metricHistogram = "histogram"
+)
+
+// DefaultMetrics holds the metrics of the fetcher, the starrer and the
+// printers, see InstrumentPrinter
+var DefaultMetrics = NewMetrics().
+	Counter(MetricRequests, "GitHub API requests by response status", "status").
+	Counter(MetricStarRetries, "Star and unstar requests retried after being rate limited", "").
+	Gauge(MetricRateLimit, "Requests left before GitHub rate limits the token", "").
+	Counter(MetricPages, "Pages of starred repositories fetched", "").
+	Histogram(MetricFetchDuration, "Time to fetch every starred repository", []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}).
+	Histogram(MetricRenderDuration, "Time to render and write the output", []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}).
+	Gauge(MetricGroupStars, "Starred repositories of every group of the last render", "group").
+	Gauge(MetricLastSuccess, "Unix time of the last successful run", "").
+	Counter(MetricRunFailures, "Failed fetch and render runs", "")
+
+// Metrics is the handful of counters, gauges and histograms of the
+// daemons written in the Prometheus text format. A counter or gauge may
+// have one label, its series are identified by the value of the label.
+type Metrics struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	kind    string
+	help    string
+	label   string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type metricSeries struct {
+	value float64
+	// counts are the observations per bucket of a histogram, not cumulative
+	counts []uint64
+	count  uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{families: make(map[string]*metricFamily)}
+}
+
+// Counter declares a counter, label is the name of its label or empty
+func (self *Metrics) Counter(name string, help string, label string) *Metrics {
+	return self.declare(name, &metricFamily{kind: metricCounter, help: help, label: label})
+}
+
+// Gauge declares a gauge, label is the name of its label or empty
+func (self *Metrics) Gauge(name string, help string, label string) *Metrics {
+	return self.declare(name, &metricFamily{kind: metricGauge, help: help, label: label})
+}
+
+// Histogram declares a histogram with the upper bounds of its buckets, the
+// +Inf bucket is implied
+func (self *Metrics) Histogram(name string, help string, buckets []float64) *Metrics {
+	return self.declare(name, &metricFamily{kind: metricHistogram, help: help, buckets: buckets})
+}
+
+func (self *Metrics) declare(name string, family *metricFamily) *Metrics {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	family.series = make(map[string]*metricSeries)
+	self.families[name] = family
+	return self
+}
+
+// Add adds value to the series of a counter or gauge with the label value
+func (self *Metrics) Add(name string, value float64, labelValue string) {
+	self.update(name, labelValue, func(family *metricFamily, series *metricSeries) {
+		series.value += value
+	})
+}
+
+// Set sets the series of a gauge with the label value
+func (self *Metrics) Set(name string, value float64, labelValue string) {
+	self.update(name, labelValue, func(family *metricFamily, series *metricSeries) {
+		series.value = value
+	})
+}
+
+// Since observes the seconds elapsed since start in a histogram
+func (self *Metrics) Since(name string, start time.Time) {
+	value := time.Since(start).Seconds()
+	self.update(name, "", func(family *metricFamily, series *metricSeries) {
+		if series.counts == nil {
+			series.counts = make([]uint64, len(family.buckets))
+		}
+		for i, bound := range family.buckets {
+			if value <= bound {
+				series.counts[i]++
+				break
+			}
+		}
+		series.count++
+		series.value += value
+	})
+}
+
+// Reset drops every series of name, e.g. before setting the gauges of the
+// current groups
+func (self *Metrics) Reset(name string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if family, ok := self.families[name]; ok {
+		family.series = make(map[string]*metricSeries)
+	}
+}
+
+// Value returns the value of a counter or gauge, or the sum of a histogram
+func (self *Metrics) Value(name string, labelValue string) float64 {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if family, ok := self.families[name]; ok {
+		if series, ok := family.series[labelValue]; ok {
+			return series.value
+		}
+	}
+	return 0
+}
+
+// update panics on an undeclared metric, a programming error rather than a
+// runtime one
+func (self *Metrics) update(name string, labelValue string, apply func(*metricFamily, *metricSeries)) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	family, ok := self.families[name]
+	if !ok {
+		panic(fmt.Sprintf(ErrorMetricUndeclared, name))
+	}
+	series, ok := family.series[labelValue]
+	if !ok {
+		series = &metricSeries{}
+		family.series[labelValue] = series
+	}
+	apply(family, series)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders the name value pairs of a series, skipping the
+// pairs without a name
+func formatLabels(pairs ...string) string {
+	var labels []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] != "" {
+			labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteTo writes every metric in the Prometheus text format, families and
+// series sorted by name
+func (self *Metrics) WriteTo(wr io.Writer) (int64, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	names := make([]string, 0, len(self.families))
+	for name := range self.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		family := self.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.kind)
+		values := make([]string, 0, len(family.series))
+		for value := range family.series {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			series := family.series[value]
+			if family.kind != metricHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", name, formatLabels(family.label, value), formatFloat(series.value))
+				continue
+			}
+			var cumulative uint64
+			for i, bound := range family.buckets {
+				cumulative += series.counts[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels("le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels("le", "+Inf"), series.count)
+			fmt.Fprintf(&b, "%s_sum %s\n", name, formatFloat(series.value))
+			fmt.Fprintf(&b, "%s_count %d\n", name, series.count)
+		}
+	}
+	n, err := io.WriteString(wr, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics to Prometheus
+func (self *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", MetricsContentType)
+	self.WriteTo(w)
+}
+
+// observeResponse counts a GitHub response by status and records the rate
+// limit it reports
+func observeResponse(resp *http.Response) {
+	DefaultMetrics.Add(MetricRequests, 1, strconv.Itoa(resp.StatusCode))
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		DefaultMetrics.Set(MetricRateLimit, float64(remaining), "")
+	}
+}
+
+// InstrumentPrinter times the renders of printer and sets the stars of
+// every group once the output is written. The result is a Renderer when
+// printer is.
+func InstrumentPrinter(printer Printer) Printer {
+	instrumented := &instrumentedPrinter{printer: printer}
+	if renderer, ok := printer.(Renderer); ok {
+		return &instrumentedRenderer{instrumentedPrinter: instrumented, renderer: renderer}
+	}
+	return instrumented
+}
+
+type instrumentedPrinter struct {
+	printer Printer
+}
+
+func (self *instrumentedPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	defer DefaultMetrics.Since(MetricRenderDuration, time.Now())
+	if err := self.printer.PrintSlice(markDownRows); err != nil {
+		return err
+	}
+	ObserveGroups(markDownRows)
+	return nil
+}
+
+// ObserveGroups sets the stars of every group, dropping the groups which
+// are gone
+func ObserveGroups(markDownRows []MarkDownRow) {
+	DefaultMetrics.Reset(MetricGroupStars)
+	for _, row := range markDownRows {
+		DefaultMetrics.Set(MetricGroupStars, float64(len(row.Repos)), row.Language)
+	}
+}
+
+type instrumentedRenderer struct {
+	*instrumentedPrinter
+	renderer Renderer
+}
+
+func (self *instrumentedRenderer) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	defer DefaultMetrics.Since(MetricRenderDuration, time.Now())
+	return self.renderer.Render(markDownRows)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+	require := require.New(t)
+	metrics := NewMetrics().
+		Counter("requests_total", "Requests by status", "status").
+		Gauge("group_stars", "Stars by group", "group").
+		Gauge("remaining", "Requests left", "").
+		Histogram("duration_seconds", "Durations", []float64{1, 3600})
+
+	metrics.Add("requests_total", 1, "200")
+	metrics.Add("requests_total", 2, "200")
+	metrics.Add("requests_total", 1, "404")
+	metrics.Set("group_stars", 2, `a"b\`)
+	metrics.Set("remaining", 4999, "")
+	metrics.Since("duration_seconds", time.Now())
+	metrics.Since("duration_seconds", time.Now().Add(-time.Minute))
+
+	var actual bytes.Buffer
+	_, err := metrics.WriteTo(&actual)
+	require.NoError(err)
+	sum := metrics.Value("duration_seconds", "")
+	require.True(sum >= 60 && sum < 61)
+	require.Equal(`# HELP duration_seconds Durations
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="1"} 1
+duration_seconds_bucket{le="3600"} 2
+duration_seconds_bucket{le="+Inf"} 2
+duration_seconds_sum `+formatFloat(sum)+`
+duration_seconds_count 2
+# HELP group_stars Stars by group
+# TYPE group_stars gauge
+group_stars{group="a\"b\\"} 2
+# HELP remaining Requests left
+# TYPE remaining gauge
+remaining 4999
+# HELP requests_total Requests by status
+# TYPE requests_total counter
+requests_total{status="200"} 3
+requests_total{status="404"} 1
+`, actual.String())
+	require.Equal(3.0, metrics.Value("requests_total", "200"))
+
+	metrics.Reset("requests_total")
+	require.Equal(0.0, metrics.Value("requests_total", "200"))
+	require.Panics(func() { metrics.Add("unknown", 1, "") })
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	require := require.New(t)
+	metrics := NewMetrics().Gauge("up", "Up", "")
+	metrics.Set("up", 1, "")
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(MetricsContentType, recorder.Header().Get("Content-Type"))
+	require.Equal("# HELP up Up\n# TYPE up gauge\nup 1\n", recorder.Body.String())
+}
+
+// failingPrinter is a Printer which is not a Renderer
+type failingPrinter struct{}
+
+func (failingPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return errors.New("disk full")
+}
+
+func TestInstrumentPrinter(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewCSVPrinter(WithCSVColumns(CSVColumns), WithCSVOutputPath("unused.csv"))
+	require.NoError(err)
+	instrumented := InstrumentPrinter(printer)
+	renderer, ok := instrumented.(Renderer)
+	require.True(ok)
+	rendered, err := renderer.Render(testStarredRows())
+	require.NoError(err)
+	expected, err := printer.Render(testStarredRows())
+	require.NoError(err)
+	require.Equal(expected, rendered)
+
+	_, ok = InstrumentPrinter(failingPrinter{}).(Renderer)
+	require.False(ok)
+	require.EqualError(InstrumentPrinter(failingPrinter{}).PrintSlice(testStarredRows()), "disk full")
+
+	ObserveGroups(testStarredRows())
+	require.Equal(1.0, DefaultMetrics.Value(MetricGroupStars, "Go"))
+	ObserveGroups(testStarredRows()[1:])
+	require.Equal(0.0, DefaultMetrics.Value(MetricGroupStars, "Go"))
+}
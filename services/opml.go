@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	DefaultOPMLTitle = "GitHub Stars releases"
+
+	// ReleasesFeed is the Atom feed GitHub publishes for every repository
+	ReleasesFeed = "/releases.atom"
+	ReleasesPage = "/releases"
+)
+
+// ensure interface implement is correct
+var _ Renderer = (*OPMLPrinter)(nil)
+
+type OPMLPrinterOption func(*OPMLPrinter)
+
+func WithOPMLTitle(title string) OPMLPrinterOption {
+	return func(opmlPrinter *OPMLPrinter) {
+		opmlPrinter.Title = title
+	}
+}
+
+// WithOPMLSkipArchived leaves archived repositories out, they will not release again
+func WithOPMLSkipArchived(skipArchived bool) OPMLPrinterOption {
+	return func(opmlPrinter *OPMLPrinter) {
+		opmlPrinter.SkipArchived = skipArchived
+	}
+}
+
+func WithOPMLOutputPath(outputPath string) OPMLPrinterOption {
+	return func(opmlPrinter *OPMLPrinter) {
+		opmlPrinter.OutputPath = outputPath
+	}
+}
+
+// OPMLPrinter writes an OPML 2.0 subscription list of the releases feed of
+// every starred repository, with one outline per group.
+type OPMLPrinter struct {
+	Title        string
+	SkipArchived bool
+	OutputPath   string
+}
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Title   string   `xml:"head>title"`
+	// Body is a struct rather than a body>outline path so that it is
+	// written even without feeds, OPML requires it
+	Body opmlBody `xml:"body"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+func NewOPMLPrinter(setters ...OPMLPrinterOption) (*OPMLPrinter, error) {
+	opmlPrinter := &OPMLPrinter{
+		Title:        DefaultOPMLTitle,
+		SkipArchived: false,
+		OutputPath:   "",
+	}
+
+	for _, setter := range setters {
+		setter(opmlPrinter)
+	}
+
+	if opmlPrinter.OutputPath == "" {
+		return nil, errors.New(ErrorOutputPath)
+	}
+
+	return opmlPrinter, nil
+}
+
+func (self *OPMLPrinter) PrintSlice(markDownRows []MarkDownRow) error {
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *OPMLPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	return renderBytes(func(wr io.Writer) error {
+		return self.Print(wr, markDownRows)
+	})
+}
+
+func (self *OPMLPrinter) Print(wr io.Writer, markDownRows []MarkDownRow) error {
+	doc := opml{
+		Version: "2.0",
+		Title:   self.Title,
+	}
+	for _, row := range markDownRows {
+		group := opmlOutline{
+			Text:  row.Language,
+			Title: row.Language,
+		}
+		for _, v := range row.Repos {
+			if self.SkipArchived && v.Archived {
+				continue
+			}
+			htmlURL := strings.TrimSuffix(v.HTMLURL, "/")
+			group.Outlines = append(group.Outlines, opmlOutline{
+				Text:    v.FullName,
+				Title:   v.FullName,
+				Type:    "rss",
+				XMLURL:  htmlURL + ReleasesFeed,
+				HTMLURL: htmlURL + ReleasesPage,
+			})
+		}
+		// a group left empty by SkipArchived would only clutter the reader
+		if len(group.Outlines) > 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, group)
+		}
+	}
+
+	if _, err := io.WriteString(wr, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(wr)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(wr, "\n")
+	return err
+}
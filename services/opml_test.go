@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOPMLPrinterWithEmptyOutputPath(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewOPMLPrinter()
+	require.EqualError(err, ErrorOutputPath)
+	require.Nil(printer)
+}
+
+func TestOPMLPrinterPrint(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewOPMLPrinter(
+		WithOPMLOutputPath("stars.opml"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+	requireGolden(t, "releases.golden.opml", output.String())
+}
+
+func TestOPMLPrinterPrintSkipArchived(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewOPMLPrinter(
+		WithOPMLSkipArchived(true),
+		WithOPMLOutputPath("stars.opml"),
+	)
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, testStarredRows())
+	require.NoError(err)
+
+	var doc opml
+	require.NoError(xml.Unmarshal([]byte(output.String()), &doc))
+	require.Len(doc.Body.Outlines, 1)
+	require.Equal("Go", doc.Body.Outlines[0].Text)
+	require.Equal("https://github.com/victorspringer/http-cache/releases.atom", doc.Body.Outlines[0].Outlines[0].XMLURL)
+}
+
+func TestOPMLPrinterPrintWithoutFeeds(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewOPMLPrinter(WithOPMLOutputPath("stars.opml"))
+	require.NoError(err)
+
+	var output strings.Builder
+	err = printer.Print(&output, nil)
+	require.NoError(err)
+	require.Contains(output.String(), "<body></body>")
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DefaultOutputMode is used when the output file does not exist yet
+const DefaultOutputMode os.FileMode = 0644
+
+// writeOutput renders into a temporary file next to outputPath and renames
+// it into place only once rendering and fsync succeed, so a failed render
+// never leaves a missing or half written output behind. The mode of an
+// existing output file is preserved.
+func writeOutput(outputPath string, render func(io.Writer) error) (err error) {
+	mode := DefaultOutputMode
+	if info, statErr := os.Stat(outputPath); statErr == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(statErr) {
+		return statErr
+	}
+
+	dir, base := filepath.Split(outputPath)
+	if dir == "" {
+		dir = "."
+	}
+	output, err := ioutil.TempFile(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			output.Close()
+			os.Remove(output.Name())
+		}
+	}()
+
+	if err = render(output); err != nil {
+		return err
+	}
+	if err = output.Chmod(mode); err != nil {
+		return err
+	}
+	if err = output.Sync(); err != nil {
+		return err
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+	return os.Rename(output.Name(), outputPath)
+}
+
+// renderBytes renders into memory, for Renderer implementations
+func renderBytes(render func(io.Writer) error) ([]byte, error) {
+	var output bytes.Buffer
+	if err := render(&output); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
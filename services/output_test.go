@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutput(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "output")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	outputPath := filepath.Join(dir, "out.md")
+
+	err = writeOutput(outputPath, func(wr io.Writer) error {
+		_, err := io.WriteString(wr, "new")
+		return err
+	})
+	require.NoError(err)
+	actual, err := ioutil.ReadFile(outputPath)
+	require.NoError(err)
+	require.Equal("new", string(actual))
+	info, err := os.Stat(outputPath)
+	require.NoError(err)
+	require.Equal(DefaultOutputMode, info.Mode().Perm())
+}
+
+func TestWriteOutputPreservesMode(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "output")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	outputPath := filepath.Join(dir, "out.md")
+	require.NoError(ioutil.WriteFile(outputPath, []byte("old"), 0600))
+	require.NoError(os.Chmod(outputPath, 0640))
+
+	err = writeOutput(outputPath, func(wr io.Writer) error {
+		_, err := io.WriteString(wr, "new")
+		return err
+	})
+	require.NoError(err)
+	info, err := os.Stat(outputPath)
+	require.NoError(err)
+	require.Equal(os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestWriteOutputKeepsPreviousFileOnError(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "output")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	outputPath := filepath.Join(dir, "out.md")
+	require.NoError(ioutil.WriteFile(outputPath, []byte("old"), 0644))
+
+	err = writeOutput(outputPath, func(wr io.Writer) error {
+		io.WriteString(wr, "half")
+		return errors.New("render failed")
+	})
+	require.EqualError(err, "render failed")
+	actual, err := ioutil.ReadFile(outputPath)
+	require.NoError(err)
+	require.Equal("old", string(actual))
+
+	// the temporary file must not be left behind
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Len(files, 1)
+}
+
+func TestWriteOutputWithMissingDirectory(t *testing.T) {
+	require := require.New(t)
+	err := writeOutput(filepath.Join(os.TempDir(), "missing-stars-dir", "out.md"), func(wr io.Writer) error {
+		return nil
+	})
+	require.Error(err)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	ErrorTemplatePack = "Unknown template pack %q, available: %s"
+
+	// TemplatePackExt is the extension of every pack file, a pack is named
+	// after its file
+	TemplatePackExt = ".md"
+)
+
+// TemplatePacks lists the names of the packs in fsys
+func TemplatePacks(fsys fs.FS) ([]string, error) {
+	files, err := fs.Glob(fsys, "*"+TemplatePackExt)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(file, TemplatePackExt))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ParseTemplatePack parses the named pack from fsys, then every override
+// file, or every file of an override directory, on top of it so that they
+// can redefine the pack's blocks.
+func ParseTemplatePack(fsys fs.FS, name string, overrides ...string) (*template.Template, error) {
+	file := name + TemplatePackExt
+	if _, err := fs.Stat(fsys, file); err != nil {
+		packs, _ := TemplatePacks(fsys)
+		return nil, fmt.Errorf(ErrorTemplatePack, name, strings.Join(packs, ", "))
+	}
+	tpl, err := template.New(path.Base(file)).Funcs(TemplateFuncs()).ParseFS(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplateOverrides(tpl, overrides...)
+}
+
+// ParseTemplateOverrides parses every override file, or every file of an
+// override directory, on top of tpl
+func ParseTemplateOverrides(tpl *template.Template, overrides ...string) (*template.Template, error) {
+	for _, override := range overrides {
+		files, err := templateFiles(override)
+		if err != nil {
+			return nil, err
+		}
+		if tpl, err = tpl.ParseFiles(files...); err != nil {
+			return nil, err
+		}
+	}
+	return tpl, nil
+}
+
+// templateFiles expands a directory into its regular files, sorted by name
+func templateFiles(name string) ([]string, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+	entries, err := ioutil.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(name, entry.Name()))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testPackFS = os.DirFS("../template")
+
+func TestTemplatePacks(t *testing.T) {
+	require := require.New(t)
+	packs, err := TemplatePacks(testPackFS)
+	require.NoError(err)
+	require.Equal([]string{"awesome", "details", "list", "starred", "table"}, packs)
+}
+
+func TestParseTemplatePackWithUnknownPack(t *testing.T) {
+	require := require.New(t)
+	tpl, err := ParseTemplatePack(testPackFS, "fancy")
+	require.EqualError(err, `Unknown template pack "fancy", available: awesome, details, list, starred, table`)
+	require.Nil(tpl)
+}
+
+func TestParseTemplatePackRendersEveryPack(t *testing.T) {
+	require := require.New(t)
+	packs, err := TemplatePacks(testPackFS)
+	require.NoError(err)
+	for _, pack := range packs {
+		tpl, err := ParseTemplatePack(testPackFS, pack)
+		require.NoError(err, pack)
+		var output strings.Builder
+		require.NoError(Print2Template(&output, tpl, testStarredRows()), pack)
+		require.Contains(output.String(), "https://github.com/victorspringer/http-cache", pack)
+	}
+}
+
+func TestParseTemplatePackList(t *testing.T) {
+	require := require.New(t)
+	tpl, err := ParseTemplatePack(testPackFS, "list")
+	require.NoError(err)
+	var output strings.Builder
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Equal(`# Stars
+
+## Go
+
+- [victorspringer/http-cache](https://github.com/victorspringer/http-cache) - High performance Golang HTTP middleware for server-side application layer caching, ideal for REST APIs
+
+## JavaScript
+
+- [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) - Caches "Google Places" \<results\>, & more
+`, output.String())
+}
+
+func TestParseTemplatePackAwesomeContents(t *testing.T) {
+	require := require.New(t)
+	tpl, err := ParseTemplatePack(testPackFS, "awesome")
+	require.NoError(err)
+	var output strings.Builder
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Contains(output.String(), "\n## "+MarkdownContents+"\n")
+}
+
+func TestParseTemplatePackWithOverrides(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "overrides")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "header.md"), []byte(`{{define "header"}}# My stars
+{{end}}`), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "repo.md"), []byte(`{{define "repo"}}* {{.FullName}}{{end}}`), 0644))
+	footer, err := ioutil.TempFile("", "footer.*.md")
+	require.NoError(err)
+	defer os.Remove(footer.Name())
+	_, err = footer.WriteString(`{{define "footer"}}
+bye
+{{end}}`)
+	require.NoError(err)
+	require.NoError(footer.Close())
+
+	// the directory overrides header and repo, the file overrides footer
+	tpl, err := ParseTemplatePack(testPackFS, "list", dir, footer.Name())
+	require.NoError(err)
+	var output strings.Builder
+	require.NoError(Print2Template(&output, tpl, testStarredRows()))
+	require.Equal("# My stars\n\n## Go\n\n* victorspringer/http-cache\n\n## JavaScript\n\n* stefanwuthrich/cached-google-places\n\nbye\n", output.String())
+}
+
+func TestParseTemplatePackWithMissingOverride(t *testing.T) {
+	require := require.New(t)
+	_, err := ParseTemplatePack(testPackFS, "list", "./no-such-template.md")
+	require.Error(err)
+}
@@ -2,28 +2,68 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"text/template"
 )
 
 const (
 	ErrorBaseTemplate = "Missing BaseTemplate"
 	ErrorOutputPath   = "Missing OutputPath"
+
+	// IndexTemplate is executed for the index of the split mode when the
+	// template defines it, splitIndexTemplate is used otherwise
+	IndexTemplate = "index"
+	// GroupTemplate is executed with the MarkDownRow of every group file of
+	// the split mode when the template defines it, splitGroupTemplate is
+	// used otherwise
+	GroupTemplate = "group"
+	// SplitManifest lists the group files the split mode wrote, only they
+	// are removed once their group is gone
+	SplitManifest = ".stars-split"
 )
 
+// splitIndexTemplate links every group file of the split mode
+var splitIndexTemplate = template.Must(template.New(IndexTemplate).Parse(`# Stars
+
+Language|⭐️
+---|---
+{{range .}}[{{.Language}}]({{.Path}})|{{.Stars}}
+{{end}}`))
+
+// splitGroupTemplate lists the repositories of a group file of the split mode
+var splitGroupTemplate = template.Must(template.New(GroupTemplate).Funcs(TemplateFuncs()).Parse(`# {{.Language}}
+
+{{range .Repos}}- [{{.FullName}}]({{.HTMLURL}}){{if .Description}} - {{mdescape .Description}}{{end}}
+{{end}}`))
+
 type Printer interface {
 	PrintSlice([]MarkDownRow) error
 }
 
+// Renderer is implemented by printers writing a single file, Render returns
+// what PrintSlice would write without touching the output.
+type Renderer interface {
+	Printer
+	Render([]MarkDownRow) ([]byte, error)
+}
+
 // ensure interface implement is correct
-var _ Printer = (*TplPrinter)(nil)
+var _ Renderer = (*TplPrinter)(nil)
 
 type TplPrinterOption func(*TplPrinter)
 
+// WithBaseTemplate takes the results of template.ParseFiles, a parse error
+// is returned by NewTplPrinter
 func WithBaseTemplate(t *template.Template, err error) TplPrinterOption {
 	return func(tplPrinter *TplPrinter) {
 		tplPrinter.BaseTemplate = t
+		tplPrinter.err = err
 	}
 }
 
@@ -33,21 +73,64 @@ func WithOutputPath(outputPath string) TplPrinterOption {
 	}
 }
 
+// WithSplitDir writes every group to <splitDir>/<group-slug>.md and an index
+// linking to them to OutputPath, splitDir is relative to the directory of
+// OutputPath
+func WithSplitDir(splitDir string) TplPrinterOption {
+	return func(tplPrinter *TplPrinter) {
+		tplPrinter.SplitDir = splitDir
+	}
+}
+
+// WithCharts also writes the svg charts into the charts directory next to
+// OutputPath, templates link them with {{chart "languages"}}
+func WithCharts(charts bool) TplPrinterOption {
+	return func(tplPrinter *TplPrinter) {
+		tplPrinter.Charts = charts
+	}
+}
+
+// WithPreviousSnapshot sets the full_name to group snapshot the changes
+// template function compares the rows with
+func WithPreviousSnapshot(previous map[string]string) TplPrinterOption {
+	return func(tplPrinter *TplPrinter) {
+		tplPrinter.Previous = previous
+	}
+}
+
 type TplPrinter struct {
 	BaseTemplate *template.Template
 	OutputPath   string
+	SplitDir     string
+	Charts       bool
+	Previous     map[string]string
+	err          error
+}
+
+// SplitGroup is a group of the split mode index along with the path of its
+// file relative to the index
+type SplitGroup struct {
+	MarkDownRow
+	Path string
 }
 
 func NewTplPrinter(setters ...TplPrinterOption) (*TplPrinter, error) {
 	tplPrinter := &TplPrinter{
 		BaseTemplate: nil,
 		OutputPath:   "",
+		SplitDir:     "",
+		Charts:       false,
+		Previous:     nil,
 	}
 
 	for _, setter := range setters {
 		setter(tplPrinter)
 	}
 
+	if tplPrinter.err != nil {
+		return nil, tplPrinter.err
+	}
+
 	if tplPrinter.BaseTemplate == nil {
 		return nil, errors.New(ErrorBaseTemplate)
 	}
@@ -60,12 +143,161 @@ func NewTplPrinter(setters ...TplPrinterOption) (*TplPrinter, error) {
 }
 
 func (self *TplPrinter) PrintSlice(markDownRows []MarkDownRow) error {
-	os.Remove(self.OutputPath)
-	output, _ := os.Create(self.OutputPath)
-	defer output.Close()
-	// ignore the error from tpl parse
-	tpl := template.Must(self.BaseTemplate, nil)
-	return Print2Template(output, tpl, markDownRows)
+	tpl, err := self.bindChanges(markDownRows)
+	if err != nil {
+		return err
+	}
+	if self.Charts {
+		stats := ComputeStats(Repos(markDownRows), DefaultStatsTop, DefaultStaleAfter)
+		if err := WriteCharts(filepath.Dir(self.OutputPath), stats); err != nil {
+			return err
+		}
+	}
+	if self.SplitDir != "" {
+		return self.printSplit(tpl, markDownRows)
+	}
+	return writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return Print2Template(wr, tpl, markDownRows)
+	})
+}
+
+func (self *TplPrinter) Render(markDownRows []MarkDownRow) ([]byte, error) {
+	tpl, err := self.bindChanges(markDownRows)
+	if err != nil {
+		return nil, err
+	}
+	return renderBytes(func(wr io.Writer) error {
+		return Print2Template(wr, tpl, markDownRows)
+	})
+}
+
+// bindChanges returns a copy of BaseTemplate whose changes template
+// function returns the delta between Previous and markDownRows. BaseTemplate
+// is left alone so that renders can run concurrently.
+func (self *TplPrinter) bindChanges(markDownRows []MarkDownRow) (*template.Template, error) {
+	changelog := NewChangelog(now(), self.Previous, markDownRows)
+	tpl, err := self.BaseTemplate.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return tpl.Funcs(template.FuncMap{
+		"changes": func() Changelog { return changelog },
+	}), nil
+}
+
+// printSplit writes one file per group then the index, and finally removes
+// the group files left over from groups which are gone. Only the files the
+// previous run listed in SplitManifest are removed, the other files of the
+// directory are left alone.
+func (self *TplPrinter) printSplit(tpl *template.Template, markDownRows []MarkDownRow) error {
+	dir := filepath.Join(filepath.Dir(self.OutputPath), self.SplitDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	previous, err := readSplitManifest(filepath.Join(dir, SplitManifest))
+	if err != nil {
+		return err
+	}
+	groupTpl, err := bindChartDir(tpl, dir, filepath.Dir(self.OutputPath))
+	if err != nil {
+		return err
+	}
+	group := splitGroupTemplate
+	if t := groupTpl.Lookup(GroupTemplate); t != nil {
+		group = t
+	}
+
+	names := make([]string, 0, len(markDownRows))
+	for _, markDownRow := range markDownRows {
+		names = append(names, markDownRow.Language)
+	}
+	written := make(map[string]bool, len(markDownRows))
+	groups := make([]SplitGroup, 0, len(markDownRows))
+	for i, slug := range UniqueSlugs(names) {
+		markDownRow := markDownRows[i]
+		err := writeOutput(filepath.Join(dir, slug+".md"), func(wr io.Writer) error {
+			return group.Execute(wr, markDownRow)
+		})
+		if err != nil {
+			return err
+		}
+		written[slug+".md"] = true
+		groups = append(groups, SplitGroup{
+			MarkDownRow: markDownRow,
+			Path:        path.Join(filepath.ToSlash(self.SplitDir), slug+".md"),
+		})
+	}
+
+	index := splitIndexTemplate
+	if t := tpl.Lookup(IndexTemplate); t != nil {
+		index = t
+	}
+	err = writeOutput(self.OutputPath, func(wr io.Writer) error {
+		return index.Execute(wr, groups)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range previous {
+		if written[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeOutput(filepath.Join(dir, SplitManifest), func(wr io.Writer) error {
+		for _, group := range groups {
+			if _, err := fmt.Fprintln(wr, path.Base(group.Path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// bindChartDir returns a copy of tpl whose chart template function links
+// the charts next to the output at outputDir from files written into dir
+func bindChartDir(tpl *template.Template, dir string, outputDir string) (*template.Template, error) {
+	rel, err := filepath.Rel(dir, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := tpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return bound.Funcs(template.FuncMap{
+		"chart": func(name string) (string, error) {
+			chartPath, err := ChartPath(name)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(filepath.ToSlash(rel), chartPath), nil
+		},
+	}), nil
+}
+
+// readSplitManifest returns the group files listed in the manifest, none
+// when it does not exist yet
+func readSplitManifest(manifestPath string) ([]string, error) {
+	content, err := ioutil.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(content), "\n") {
+		// only group files, an edited manifest cannot reach outside the
+		// directory
+		if filepath.Ext(name) == ".md" && name == filepath.Base(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
 
 func Print2Template(
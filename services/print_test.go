@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -115,3 +116,216 @@ func TestPrintSlice(t *testing.T) {
 
 	require.Equal("![test](https://github.com/AlphaWong/Stars/workflows/test/badge.svg)[![codecov](https://codecov.io/gh/AlphaWong/Stars/branch/master/graph/badge.svg?token=xuILexY8TD)](https://codecov.io/gh/AlphaWong/Stars)\n# Stars\nDo you remember what you star ?\n\n# update\nchange to async request instead waterflow now.\n\n# Run \n```sh\nTOKEN=<GITHUB_TOKEN> go run ./main.go && cp -f ./out.md ./README.md\n```\n\n# GITHUB_TOKEN\n```\nsee https://github.com/settings/tokens\n```\n\n# Github doc\n```\nhttps://docs.github.com/en/free-pro-team@latest/rest/reference/activity#list-repositories-starred-by-a-user\n```\n# Result\nLanguage|⭐️|Repos\n---|---|---\nGo|1|[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]\nJavaScript|2|[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ], [ [z](zxy) ]\n", string(actual))
 }
+
+// testStarredRows builds grouped rows with the repository details the
+// non-template printers rely on.
+func testStarredRows() []MarkDownRow {
+	httpCache := Repository{
+		ID:              73893472,
+		NodeID:          "MDEwOlJlcG9zaXRvcnk3Mzg5MzQ3Mg==",
+		Name:            "http-cache",
+		FullName:        "victorspringer/http-cache",
+		HTMLURL:         "https://github.com/victorspringer/http-cache",
+		Description:     "High performance Golang HTTP middleware for server-side application layer caching, ideal for REST APIs",
+		Language:        "Go",
+		StargazersCount: 183,
+		ForksCount:      20,
+		Topics:          []string{"cache", "golang"},
+		PushedAt:        time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC),
+		StarredAt:       time.Date(2021, 3, 18, 9, 30, 0, 0, time.UTC),
+	}
+	httpCache.Owner.Login = "victorspringer"
+	httpCache.License.SpdxID = "MIT"
+	places := Repository{
+		ID:              334331282,
+		NodeID:          "MDEwOlJlcG9zaXRvcnkzMzQzMzEyODI=",
+		Name:            "cached-google-places",
+		FullName:        "stefanwuthrich/cached-google-places",
+		HTMLURL:         "https://github.com/stefanwuthrich/cached-google-places",
+		Description:     `Caches "Google Places" <results>, & more`,
+		Language:        "JavaScript",
+		StargazersCount: 1,
+		Archived:        true,
+		PushedAt:        time.Date(2021, 2, 1, 8, 0, 0, 0, time.UTC),
+		StarredAt:       time.Date(2021, 3, 19, 12, 0, 0, 0, time.UTC),
+	}
+	places.Owner.Login = "stefanwuthrich"
+	return []MarkDownRow{
+		{
+			Language: "Go",
+			Stars:    "1",
+			Items:    "[ [victorspringer/http-cache](https://github.com/victorspringer/http-cache) ]",
+			Repos:    UserStarredRepositories{httpCache},
+		},
+		{
+			Language: "JavaScript",
+			Stars:    "1",
+			Items:    "[ [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) ]",
+			Repos:    UserStarredRepositories{places},
+		},
+	}
+}
+
+func TestPrintSliceKeepsPreviousOutputOnTemplateError(t *testing.T) {
+	require := require.New(t)
+	tmpfile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("previous README")
+	require.NoError(err)
+	require.NoError(tmpfile.Close())
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Parse(`{{define "layout"}}{{.Missing}}{{end}}`)),
+		WithOutputPath(tmpfile.Name()),
+	)
+	require.NoError(err)
+
+	err = printer.PrintSlice(testStarredRows())
+	require.Error(err)
+	actual, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(err)
+	require.Equal("previous README", string(actual))
+}
+
+func TestNewTplPrinterWithTemplateParseError(t *testing.T) {
+	require := require.New(t)
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.ParseFiles("../template/missing.md")),
+		WithOutputPath("out.md"),
+	)
+	require.Error(err)
+	require.NotEqual(ErrorBaseTemplate, err.Error())
+	require.Nil(printer)
+}
+
+func TestPrintSliceWithSplitDir(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "split")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Parse(`{{define "layout"}}{{range .}}{{.Language}}: {{.Stars}}{{end}}{{end}}{{define "group"}}{{.Language}}: {{.Stars}}{{end}}`)),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithSplitDir("stars"),
+	)
+	require.NoError(err)
+	// a previous run wrote a group which is gone
+	require.NoError(printer.PrintSlice(append(testStarredRows(), MarkDownRow{Language: "Rust"})))
+	stale := filepath.Join(dir, "stars", "rust.md")
+	require.FileExists(stale)
+	// hand written next to the group files
+	foreign := filepath.Join(dir, "stars", "notes.md")
+	require.NoError(ioutil.WriteFile(foreign, []byte("notes"), 0644))
+
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(err)
+	require.Equal("# Stars\n\nLanguage|⭐️\n---|---\n[Go](stars/go.md)|1\n[JavaScript](stars/javascript.md)|1\n", string(index))
+
+	group, err := ioutil.ReadFile(filepath.Join(dir, "stars", "go.md"))
+	require.NoError(err)
+	require.Equal("Go: 1", string(group))
+
+	_, err = os.Stat(stale)
+	require.True(os.IsNotExist(err))
+	require.FileExists(foreign)
+
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, "stars", SplitManifest))
+	require.NoError(err)
+	require.Equal("go.md\njavascript.md\n", string(manifest))
+}
+
+func TestPrintSliceWithSplitDirAndDefaultPack(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "split")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(ParseTemplatePack(testPackFS, "starred")),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithSplitDir("stars"),
+	)
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	// the group files only list their group, not the layout around it
+	group, err := ioutil.ReadFile(filepath.Join(dir, "stars", "javascript.md"))
+	require.NoError(err)
+	require.Equal(`# JavaScript
+
+- [stefanwuthrich/cached-google-places](https://github.com/stefanwuthrich/cached-google-places) - Caches "Google Places" \<results\>, & more
+`, string(group))
+}
+
+func TestPrintSliceWithSplitDirNextToIndex(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "split")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	contributing := filepath.Join(dir, "CONTRIBUTING.md")
+	require.NoError(ioutil.WriteFile(contributing, []byte("contributing"), 0644))
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Parse(`{{define "layout"}}{{range .}}{{.Language}}{{end}}{{end}}`)),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithSplitDir("."),
+	)
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+	require.NoError(printer.PrintSlice(testStarredRows()[:1]))
+
+	require.FileExists(filepath.Join(dir, "README.md"))
+	require.FileExists(contributing)
+	require.FileExists(filepath.Join(dir, "go.md"))
+	_, err = os.Stat(filepath.Join(dir, "javascript.md"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestPrintSliceWithSplitDirAndIndexTemplate(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "split")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Parse(`{{define "layout"}}{{end}}{{define "index"}}{{range .}}{{.Path}} {{end}}{{end}}`)),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithSplitDir("stars"),
+	)
+	require.NoError(err)
+	markDownRows := append(testStarredRows(), MarkDownRow{Language: "go"})
+	require.NoError(printer.PrintSlice(markDownRows))
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(err)
+	require.Equal("stars/go.md stars/javascript.md stars/go-2.md ", string(index))
+}
+
+func TestPrintSliceWithSplitDirAndCharts(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "split")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	printer, err := NewTplPrinter(
+		WithBaseTemplate(template.New("layout").Funcs(TemplateFuncs()).Parse(`{{define "layout"}}{{end}}{{define "index"}}{{chart "languages"}}{{end}}{{define "group"}}{{chart "languages"}}{{end}}`)),
+		WithOutputPath(filepath.Join(dir, "README.md")),
+		WithSplitDir("docs/stars"),
+		WithCharts(true),
+	)
+	require.NoError(err)
+	require.NoError(printer.PrintSlice(testStarredRows()))
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(err)
+	require.Equal("charts/languages.svg", string(index))
+	group, err := ioutil.ReadFile(filepath.Join(dir, "docs", "stars", "go.md"))
+	require.NoError(err)
+	require.Equal("../../charts/languages.svg", string(group))
+	require.FileExists(filepath.Join(dir, "docs", "stars", string(group)))
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ErrorCron      = "Invalid cron expression %q, want minute hour day month weekday"
+	ErrorCronField = "Invalid cron field %q, want a value within %d-%d"
+	ErrorInterval  = "Invalid interval %s, want a positive duration"
+
+	// cronSearchYears bounds the search of the next time an expression such
+	// as "0 0 30 2 *" never matches
+	cronSearchYears = 5
+)
+
+// Schedule returns the first run time strictly after t, zero if none
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ensure interface implement is correct
+var _ Schedule = IntervalSchedule(0)
+var _ Schedule = (*CronSchedule)(nil)
+
+// IntervalSchedule runs every interval
+type IntervalSchedule time.Duration
+
+func NewIntervalSchedule(interval time.Duration) (IntervalSchedule, error) {
+	if interval <= 0 {
+		return 0, fmt.Errorf(ErrorInterval, interval)
+	}
+	return IntervalSchedule(interval), nil
+}
+
+func (self IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(self))
+}
+
+// CronSchedule is a standard five field cron expression, each field is a
+// bit set of the values it matches
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar follow cron: when both day fields are restricted
+	// a day matching either runs
+	domStar, dowStar bool
+	location         *time.Location
+}
+
+// ParseCron parses "minute hour day-of-month month day-of-week", fields take
+// *, values, ranges a-b, steps */n or a-b/n and comma separated lists.
+// Sunday is 0 or 7. Times are matched in loc.
+func ParseCron(expr string, loc *time.Location) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf(ErrorCron, expr)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &CronSchedule{
+		minute:   sets[0],
+		hour:     sets[1],
+		dom:      sets[2],
+		month:    sets[3],
+		dow:      sets[4],
+		domStar:  strings.HasPrefix(fields[2], "*"),
+		dowStar:  strings.HasPrefix(fields[4], "*"),
+		location: loc,
+	}, nil
+}
+
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		valueRange, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf(ErrorCronField, field, min, max)
+			}
+			valueRange, step = part[:i], n
+		}
+		low, high := min, max
+		if valueRange != "*" {
+			bounds := strings.SplitN(valueRange, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf(ErrorCronField, field, min, max)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf(ErrorCronField, field, min, max)
+				}
+			} else if step > 1 {
+				// "5/15" runs from 5 to the end of the range
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf(ErrorCronField, field, min, max)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Next steps through the wall clock of the location, a zone offset which is
+// not a whole number of hours, e.g. Asia/Kolkata, still meets minute 0
+func (self *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(self.location)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, self.location)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case self.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, self.location)
+		case !self.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, self.location)
+		case self.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, self.location)
+		case self.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (self *CronSchedule) matchDay(t time.Time) bool {
+	dom := self.dom&(1<<uint(t.Day())) != 0
+	dow := self.dow&(1<<uint(t.Weekday())) != 0
+	if self.domStar || self.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalSchedule(t *testing.T) {
+	require := require.New(t)
+	schedule, err := NewIntervalSchedule(time.Hour)
+	require.NoError(err)
+	at := time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+	require.Equal(at.Add(time.Hour), schedule.Next(at))
+
+	_, err = NewIntervalSchedule(0)
+	require.EqualError(err, "Invalid interval 0s, want a positive duration")
+}
+
+func TestParseCron(t *testing.T) {
+	require := require.New(t)
+	at := time.Date(2026, 10, 19, 12, 30, 15, 0, time.UTC) // a Monday
+	for expr, expected := range map[string]time.Time{
+		"* * * * *":       time.Date(2026, 10, 19, 12, 31, 0, 0, time.UTC),
+		"*/15 * * * *":    time.Date(2026, 10, 19, 12, 45, 0, 0, time.UTC),
+		"0 */6 * * *":     time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC),
+		"5/20 9-17 * * *": time.Date(2026, 10, 19, 12, 45, 0, 0, time.UTC),
+		"0 3 * * 0":       time.Date(2026, 10, 25, 3, 0, 0, 0, time.UTC),
+		"0 3 * * 7":       time.Date(2026, 10, 25, 3, 0, 0, 0, time.UTC),
+		"0 0 1 1,7 *":     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		"30 12 19 10 *":   time.Date(2027, 10, 19, 12, 30, 0, 0, time.UTC),
+		// both day fields restricted, either matches
+		"0 0 1 * 3":  time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *": time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+	} {
+		schedule, err := ParseCron(expr, time.UTC)
+		require.NoError(err, expr)
+		require.Equal(expected, schedule.Next(at), expr)
+	}
+
+	schedule, err := ParseCron("0 0 30 2 *", time.UTC)
+	require.NoError(err)
+	require.True(schedule.Next(at).IsZero())
+}
+
+func TestParseCronWithHalfHourOffset(t *testing.T) {
+	require := require.New(t)
+	// Asia/Kolkata
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	schedule, err := ParseCron("0 12 * * *", kolkata)
+	require.NoError(err)
+	at := time.Date(2026, 10, 19, 12, 30, 15, 0, time.UTC)
+	require.Equal(time.Date(2026, 10, 20, 12, 0, 0, 0, kolkata), schedule.Next(at))
+
+	schedule, err = ParseCron("15 */6 * * *", kolkata)
+	require.NoError(err)
+	require.Equal(time.Date(2026, 10, 19, 18, 15, 0, 0, kolkata), schedule.Next(at))
+}
+
+func TestParseCronWithInvalidExpression(t *testing.T) {
+	require := require.New(t)
+	_, err := ParseCron("* * * *", time.UTC)
+	require.EqualError(err, `Invalid cron expression "* * * *", want minute hour day month weekday`)
+	for _, expr := range []string{"60 * * * *", "* 5-1 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "* * * 1-x *"} {
+		_, err := ParseCron(expr, time.UTC)
+		require.Error(err, expr)
+	}
+	_, err = ParseCron("* 24 * * *", time.UTC)
+	require.EqualError(err, `Invalid cron field "24", want a value within 0-23`)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// slugReplacer keeps languages such as C, C# and C++ apart once slugified
+var slugReplacer = strings.NewReplacer(
+	"+", "-plus",
+	"#", "-sharp",
+)
+
+// Slugify turns a group name into a lowercase, file-name safe slug.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range slugReplacer.Replace(strings.ToLower(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "group"
+	}
+	return slug
+}
+
+// UniqueSlugs slugifies names in order, suffixing repeats with -2, -3, ...
+// so the same input always produces the same slugs.
+func UniqueSlugs(names []string) []string {
+	used := make(map[string]bool, len(names))
+	slugs := make([]string, 0, len(names))
+	for _, name := range names {
+		base := Slugify(name)
+		slug := base
+		for n := 2; used[slug]; n++ {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+		used[slug] = true
+		slugs = append(slugs, slug)
+	}
+	return slugs
+}
+
+// GitHubAnchor returns the id GitHub gives a markdown heading: lowercase,
+// punctuation and symbols dropped, spaces turned into hyphens.
+func GitHubAnchor(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// GitHubAnchors returns the ids of headings rendered in order on the same
+// page, GitHub suffixes repeated ids with -1, -2, ...
+func GitHubAnchors(headings []string) []string {
+	used := make(map[string]bool, len(headings))
+	anchors := make([]string, 0, len(headings))
+	for _, heading := range headings {
+		base := GitHubAnchor(heading)
+		anchor := base
+		for n := 1; used[anchor]; n++ {
+			anchor = base + "-" + strconv.Itoa(n)
+		}
+		used[anchor] = true
+		anchors = append(anchors, anchor)
+	}
+	return anchors
+}
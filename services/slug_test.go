@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	require := require.New(t)
+	require.Equal("go", Slugify("Go"))
+	require.Equal("c", Slugify("C"))
+	require.Equal("c-plus-plus", Slugify("C++"))
+	require.Equal("c-sharp", Slugify("C#"))
+	require.Equal("vim-script", Slugify("Vim script"))
+	require.Equal("objective-c", Slugify("Objective-C"))
+	require.Equal("group", Slugify("!!!"))
+}
+
+func TestUniqueSlugs(t *testing.T) {
+	require := require.New(t)
+	require.Equal(
+		[]string{"go", "go-2", "f-sharp", "go-3"},
+		UniqueSlugs([]string{"Go", "go", "F#", "GO"}),
+	)
+}
+
+func TestUniqueSlugsWithSuffixLikeName(t *testing.T) {
+	require := require.New(t)
+	require.Equal(
+		[]string{"go", "go-2", "go-3"},
+		UniqueSlugs([]string{"Go", "Go 2", "go"}),
+	)
+}
+
+func TestGitHubAnchor(t *testing.T) {
+	require := require.New(t)
+	require.Equal("go", GitHubAnchor("Go"))
+	require.Equal("c", GitHubAnchor("C++"))
+	require.Equal("vim-script", GitHubAnchor("Vim script"))
+	require.Equal("jupyter-notebook-12", GitHubAnchor("Jupyter Notebook (12)"))
+	require.Equal("-go", GitHubAnchor("🐹 Go"))
+	require.Equal("snake_case", GitHubAnchor("snake_case"))
+}
+
+func TestGitHubAnchors(t *testing.T) {
+	require := require.New(t)
+	require.Equal(
+		[]string{"stars", "c", "c-1", "c-2", "stars-1", "go"},
+		GitHubAnchors([]string{"Stars", "C", "C++", "C#", "Stars", "Go"}),
+	)
+}
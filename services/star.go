@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// GithubStarURI stars with PUT and unstars with DELETE
+	// "https://api.github.com/user/starred/alphawong/Stars"
+	GithubStarURI = "https://api.github.com/user/starred/%s"
+	// GithubGraphQLURI resolves the node_id of renamed repositories and
+	// reads and writes the star lists
+	GithubGraphQLURI = "https://api.github.com/graphql"
+
+	ErrorRepoName   = "Invalid repository %q, want owner/repo"
+	ErrorFilter     = "Invalid filter %q, want field=value"
+	ErrorStarStatus = "Unexpected status %d"
+	ErrorNotFound   = "Repository not found"
+	ErrorGraphQL    = "GraphQL error: %s"
+
+	ActionStar   = "star"
+	ActionUnstar = "unstar"
+
+	StarResultDone   = "done"
+	StarResultDryRun = "dry-run"
+	StarResultFailed = "failed"
+
+	// HealthField filters on the kind of health issue, see CheckHealth
+	HealthField = "health"
+
+	// starRetries is how many times a rate limited request is retried
+	starRetries = 3
+)
+
+// ErrNotFound is returned when a repository does not exist, it was
+// deleted, made private or renamed
+var ErrNotFound = errors.New(ErrorNotFound)
+
+// nodeQuery looks up the current name of a repository by its node_id
+const nodeQuery = `query($id: ID!) { node(id: $id) { ... on Repository { nameWithOwner } } }`
+
+var repoName = regexp.MustCompile(`^(?:https?://github\.com/)?([\w.-]+/[\w.-]+?)(?:\.git)?/?$`)
+
+// ensure interface implement is correct
+var _ Starrer = (*GitHubStarrer)(nil)
+var _ NodeResolver = (*GitHubStarrer)(nil)
+
+type Starrer interface {
+	Star(fullName string) error
+	Unstar(fullName string) error
+}
+
+// NodeResolver is implemented by the starrers which can find the current
+// name of a renamed repository from its node_id
+type NodeResolver interface {
+	ResolveNode(nodeID string) (string, error)
+}
+
+type GitHubStarrerOption func(*GitHubStarrer)
+
+func WithStarrerToken(token string) GitHubStarrerOption {
+	return func(g *GitHubStarrer) {
+		g.Token = token
+	}
+}
+
+// WithStarrerInterval spaces requests out, GitHub asks for at least a
+// second between mutating requests
+func WithStarrerInterval(interval time.Duration) GitHubStarrerOption {
+	return func(g *GitHubStarrer) {
+		g.Interval = interval
+	}
+}
+
+// GitHubStarrer stars and unstars repositories as the owner of Token. It
+// waits Interval between requests and, once the rate limit is exhausted,
+// until GitHub resets it.
+type GitHubStarrer struct {
+	Token    string
+	Interval time.Duration
+	H        *http.Client
+	// next is the earliest time the next request may be sent
+	next  time.Time
+	sleep func(time.Duration)
+	// lists are the ids of the star lists of the owner of Token by name,
+	// loaded by the first SetStarLists
+	lists map[string]string
+}
+
+// StarResult is the outcome of starring or unstarring one repository
+type StarResult struct {
+	FullName string `json:"full_name"`
+	Action   string `json:"action"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+}
+
+func NewGitHubStarrer(setters ...GitHubStarrerOption) (*GitHubStarrer, error) {
+	g := &GitHubStarrer{
+		Token:    "",
+		Interval: 0,
+		H:        &http.Client{},
+		sleep:    time.Sleep,
+	}
+
+	for _, setter := range setters {
+		setter(g)
+	}
+
+	if g.Token == "" {
+		return nil, errors.New(ErrorGithubToken)
+	}
+
+	return g, nil
+}
+
+func (self *GitHubStarrer) Star(fullName string) error {
+	return self.do(http.MethodPut, fullName)
+}
+
+func (self *GitHubStarrer) Unstar(fullName string) error {
+	return self.do(http.MethodDelete, fullName)
+}
+
+func (self *GitHubStarrer) do(method string, fullName string) error {
+	for attempt := 0; ; attempt++ {
+		if wait := self.next.Sub(now()); wait > 0 {
+			self.sleep(wait)
+		}
+		req, _ := http.NewRequest(method, fmt.Sprintf(GithubStarURI, fullName), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		// GitHub requires a zero length body on PUT
+		req.ContentLength = 0
+		resp, err := self.H.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		observeResponse(resp)
+
+		self.next = now().Add(self.Interval)
+		limited := rateLimitReset(resp.Header)
+		if limited.After(self.next) {
+			self.next = limited
+		}
+		switch {
+		case resp.StatusCode == http.StatusNoContent:
+			return nil
+		case resp.StatusCode == http.StatusNotFound:
+			return ErrNotFound
+		case !limited.IsZero() && attempt < starRetries &&
+			(resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests):
+			DefaultMetrics.Add(MetricStarRetries, 1, "")
+			continue
+		default:
+			return fmt.Errorf(ErrorStarStatus, resp.StatusCode)
+		}
+	}
+}
+
+// ResolveNode returns the current owner/repo of the repository with
+// nodeID, ErrNotFound when it does not exist anymore
+func (self *GitHubStarrer) ResolveNode(nodeID string) (string, error) {
+	var data struct {
+		Node *struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"node"`
+	}
+	// an unknown node_id answers a null node along with an error
+	_, err := self.postGraphQL(nodeQuery, map[string]interface{}{"id": nodeID}, &data)
+	if err != nil {
+		return "", err
+	}
+	if data.Node == nil || data.Node.NameWithOwner == "" {
+		return "", ErrNotFound
+	}
+	return data.Node.NameWithOwner, nil
+}
+
+// graphQL runs query and decodes its data into data, the errors GraphQL
+// answers along with it fail the query
+func (self *GitHubStarrer) graphQL(query string, variables map[string]interface{}, data interface{}) error {
+	messages, err := self.postGraphQL(query, variables, data)
+	if err != nil {
+		return err
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf(ErrorGraphQL, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// postGraphQL runs query, decodes its data into data and returns the
+// messages of the errors GraphQL answered along with it
+func (self *GitHubStarrer) postGraphQL(query string, variables map[string]interface{}, data interface{}) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest(http.MethodPost, GithubGraphQLURI, bytes.NewReader(body))
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", self.Token))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := self.H.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	observeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(ErrorStarStatus, resp.StatusCode)
+	}
+
+	result := struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	messages := make([]string, 0, len(result.Errors))
+	for _, v := range result.Errors {
+		messages = append(messages, v.Message)
+	}
+	return messages, nil
+}
+
+// rateLimitReset returns when a rate limited client may send again, from
+// Retry-After or from X-RateLimit-Reset once X-RateLimit-Remaining is 0. It
+// is zero when the client is not limited.
+func rateLimitReset(header http.Header) time.Time {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return now().Add(time.Duration(seconds) * time.Second)
+	}
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(reset, 0)
+}
+
+// ApplyStars stars or unstars every repository in order, a failure does not
+// stop the ones after it. Nothing is sent on dryRun.
+func ApplyStars(starrer Starrer, action string, fullNames []string, dryRun bool) []StarResult {
+	apply := starrer.Star
+	if action == ActionUnstar {
+		apply = starrer.Unstar
+	}
+	results := make([]StarResult, 0, len(fullNames))
+	for _, fullName := range fullNames {
+		result := StarResult{FullName: fullName, Action: action, Result: StarResultDryRun}
+		if !dryRun {
+			result.Result = StarResultDone
+			if err := apply(fullName); err != nil {
+				result.Result = StarResultFailed
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ParseRepoName accepts owner/repo or a github.com url and returns owner/repo
+func ParseRepoName(s string) (string, error) {
+	match := repoName.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return "", fmt.Errorf(ErrorRepoName, s)
+	}
+	return match[1], nil
+}
+
+// ReadRepoNames reads one repository per line, blank lines and lines
+// starting with # are skipped
+func ReadRepoNames(r io.Reader) ([]string, error) {
+	var fullNames []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fullName, err := ParseRepoName(line)
+		if err != nil {
+			return nil, err
+		}
+		fullNames = append(fullNames, fullName)
+	}
+	return fullNames, scanner.Err()
+}
+
+// FilterRepos keeps the repositories matching every comma separated
+// field=value of expr, the fields are the ones of the where template
+// function plus health, e.g. "health=archived,language=Go".
+func FilterRepos(expr string, repositories UserStarredRepositories) (UserStarredRepositories, error) {
+	for _, condition := range strings.Split(expr, ",") {
+		parts := strings.SplitN(condition, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf(ErrorFilter, expr)
+		}
+		field, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if field != HealthField {
+			filtered, err := WhereRepos(field, value, repositories)
+			if err != nil {
+				return nil, err
+			}
+			repositories = filtered
+			continue
+		}
+		kinds := make(map[string]bool)
+		for _, issue := range Unhealthy(repositories) {
+			if issue.Kind == value {
+				kinds[issue.FullName] = true
+			}
+		}
+		var filtered UserStarredRepositories
+		for _, v := range repositories {
+			if kinds[v.FullName] {
+				filtered = append(filtered, v)
+			}
+		}
+		repositories = filtered
+	}
+	return repositories, nil
+}
+
+// WriteStarResults prints the results as an aligned terminal table
+func WriteStarResults(wr io.Writer, results []StarResult) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Result\tAction\tRepository\tError")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Result, result.Action, result.FullName, result.Error)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGitHubStarrerWithEmptyToken(t *testing.T) {
+	require := require.New(t)
+	starrer, err := NewGitHubStarrer()
+	require.EqualError(err, ErrorGithubToken)
+	require.Nil(starrer)
+}
+
+func TestParseRepoName(t *testing.T) {
+	require := require.New(t)
+	for input, expected := range map[string]string{
+		"AlphaWong/Stars":                        "AlphaWong/Stars",
+		" go-playground/validator ":              "go-playground/validator",
+		"https://github.com/AlphaWong/Stars":     "AlphaWong/Stars",
+		"https://github.com/AlphaWong/Stars/":    "AlphaWong/Stars",
+		"https://github.com/AlphaWong/Stars.git": "AlphaWong/Stars",
+		"https://github.com/a/socket.io":         "a/socket.io",
+	} {
+		actual, err := ParseRepoName(input)
+		require.NoError(err, input)
+		require.Equal(expected, actual, input)
+	}
+	for _, input := range []string{"Stars", "a/b/c", "https://gitlab.com/a/b", ""} {
+		_, err := ParseRepoName(input)
+		require.Error(err, input)
+	}
+}
+
+func TestReadRepoNames(t *testing.T) {
+	require := require.New(t)
+	fullNames, err := ReadRepoNames(strings.NewReader("# dead repos\na/b\n\nhttps://github.com/c/d\n"))
+	require.NoError(err)
+	require.Equal([]string{"a/b", "c/d"}, fullNames)
+
+	_, err = ReadRepoNames(strings.NewReader("a/b\nnot a repo\n"))
+	require.EqualError(err, `Invalid repository "not a repo", want owner/repo`)
+}
+
+func TestFilterRepos(t *testing.T) {
+	require := require.New(t)
+	repos := Repos(testStarredRows())
+
+	filtered, err := FilterRepos("health=archived", repos)
+	require.NoError(err)
+	require.Len(filtered, 1)
+	require.Equal("stefanwuthrich/cached-google-places", filtered[0].FullName)
+
+	filtered, err = FilterRepos("language=Go, topics=cache", repos)
+	require.NoError(err)
+	require.Len(filtered, 1)
+	require.Equal("victorspringer/http-cache", filtered[0].FullName)
+
+	filtered, err = FilterRepos("health=archived,language=Go", repos)
+	require.NoError(err)
+	require.Empty(filtered)
+
+	_, err = FilterRepos("archived", repos)
+	require.EqualError(err, `Invalid filter "archived", want field=value`)
+	_, err = FilterRepos("owner=a", repos)
+	require.EqualError(err, `Unknown repository field "owner"`)
+}
+
+func TestApplyStars(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodDelete, "https://api.github.com/user/starred/a/b",
+		func(req *http.Request) (*http.Response, error) {
+			require.Equal("token TOKEN", req.Header.Get("Authorization"))
+			return httpmock.NewStringResponse(http.StatusNoContent, ""), nil
+		})
+	httpmock.RegisterResponder(http.MethodDelete, "https://api.github.com/user/starred/a/gone",
+		httpmock.NewStringResponder(http.StatusNotFound, ""))
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/a/b",
+		httpmock.NewStringResponder(http.StatusNoContent, ""))
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	require.Equal([]StarResult{
+		{FullName: "a/b", Action: ActionUnstar, Result: StarResultDone},
+		{FullName: "a/gone", Action: ActionUnstar, Result: StarResultFailed, Error: ErrorNotFound},
+	}, ApplyStars(starrer, ActionUnstar, []string{"a/b", "a/gone"}, false))
+	require.Equal([]StarResult{
+		{FullName: "a/b", Action: ActionStar, Result: StarResultDone},
+	}, ApplyStars(starrer, ActionStar, []string{"a/b"}, false))
+	require.Equal(3, httpmock.GetTotalCallCount())
+
+	require.Equal([]StarResult{
+		{FullName: "a/b", Action: ActionStar, Result: StarResultDryRun},
+	}, ApplyStars(starrer, ActionStar, []string{"a/b"}, true))
+	require.Equal(3, httpmock.GetTotalCallCount())
+}
+
+func TestWriteStarResults(t *testing.T) {
+	require := require.New(t)
+	var output strings.Builder
+	require.NoError(WriteStarResults(&output, []StarResult{
+		{FullName: "a/gone", Action: ActionUnstar, Result: StarResultFailed, Error: ErrorNotFound},
+	}))
+	require.Equal("Result  Action  Repository  Error\nfailed  unstar  a/gone      Repository not found\n", output.String())
+}
+
+func TestGitHubStarrerWaitsForRateLimit(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer func() {
+		now = time.Now
+	}()
+	current := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	calls := 0
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/a/b",
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				resp := httpmock.NewStringResponse(http.StatusForbidden, "")
+				resp.Header.Set("X-RateLimit-Remaining", "0")
+				resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(current.Add(time.Minute).Unix(), 10))
+				return resp, nil
+			}
+			return httpmock.NewStringResponse(http.StatusNoContent, ""), nil
+		})
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"), WithStarrerInterval(time.Second))
+	require.NoError(err)
+	var slept []time.Duration
+	starrer.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		current = current.Add(d)
+	}
+	require.NoError(starrer.Star("a/b"))
+	require.NoError(starrer.Star("a/b"))
+	require.Equal([]time.Duration{time.Minute, time.Second}, slept)
+}
+
+func TestGitHubStarrerGivesUpAfterRetries(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodPut, "https://api.github.com/user/starred/a/b",
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusTooManyRequests, "")
+			resp.Header.Set("Retry-After", "0")
+			return resp, nil
+		})
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	retries := DefaultMetrics.Value(MetricStarRetries, "")
+	require.EqualError(starrer.Star("a/b"), "Unexpected status 429")
+	require.Equal(starRetries+1, httpmock.GetTotalCallCount())
+	require.Equal(retries+starRetries, DefaultMetrics.Value(MetricStarRetries, ""))
+}
+
+func TestGitHubStarrerResolveNode(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(http.MethodPost, GithubGraphQLURI,
+		func(req *http.Request) (*http.Response, error) {
+			require.Equal("token TOKEN", req.Header.Get("Authorization"))
+			var body struct {
+				Variables map[string]string `json:"variables"`
+			}
+			require.NoError(json.NewDecoder(req.Body).Decode(&body))
+			if body.Variables["id"] == "NODE42" {
+				return httpmock.NewStringResponse(http.StatusOK, `{"data": {"node": {"nameWithOwner": "a/new-name"}}}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"data": {"node": null}, "errors": [{"type": "NOT_FOUND"}]}`), nil
+		})
+
+	starrer, err := NewGitHubStarrer(WithStarrerToken("TOKEN"))
+	require.NoError(err)
+	fullName, err := starrer.ResolveNode("NODE42")
+	require.NoError(err)
+	require.Equal("a/new-name", fullName)
+	_, err = starrer.ResolveNode("NODE7")
+	require.True(errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// DefaultStaleAfter is how long without a push makes a repository stale
+	DefaultStaleAfter = 365 * 24 * time.Hour
+	DefaultStatsTop   = 10
+	// NoLicense groups repositories without a detected license
+	NoLicense = "None"
+	// growthLayout buckets the growth over time by month
+	growthLayout = "2006-01"
+)
+
+// Count is the number of starred repositories sharing a name
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats summarises the starred repositories, the counts are sorted by
+// count descending then name except Years and Growth which are sorted by
+// date.
+type Stats struct {
+	Total     int     `json:"total"`
+	Languages []Count `json:"languages"`
+	Owners    []Count `json:"owners"`
+	TopOwners []Count `json:"top_owners"`
+	Licenses  []Count `json:"licenses"`
+	// Years counts repositories by the year they were starred
+	Years    []Count `json:"years"`
+	Archived int     `json:"archived"`
+	// Stale counts the repositories, archived or not, not pushed to for
+	// longer than the stale duration
+	Stale                  int     `json:"stale"`
+	ArchivedOrStale        int     `json:"archived_or_stale"`
+	ArchivedOrStalePercent float64 `json:"archived_or_stale_percent"`
+	MedianStargazers       float64 `json:"median_stargazers"`
+	// Growth is the running total of starred repositories at the end of
+	// every month a repository was starred in
+	Growth []Count `json:"growth"`
+}
+
+// ComputeStats summarises repositories, top limits TopOwners and staleAfter
+// is the time since the last push after which a repository is stale.
+func ComputeStats(userStarredRepositories UserStarredRepositories, top int, staleAfter time.Duration) Stats {
+	languages := make(map[string]int)
+	owners := make(map[string]int)
+	licenses := make(map[string]int)
+	years := make(map[string]int)
+	months := make(map[string]int)
+	stargazers := make([]int, 0, len(userStarredRepositories))
+	stats := Stats{Total: len(userStarredRepositories)}
+
+	staleBefore := now().Add(-staleAfter)
+	for _, v := range userStarredRepositories {
+		languages[LanguageKey(v)]++
+		owners[OwnerKey(v)]++
+		license := v.License.SpdxID
+		if license == "" {
+			license = NoLicense
+		}
+		licenses[license]++
+		if !v.StarredAt.IsZero() {
+			years[strconv.Itoa(v.StarredAt.UTC().Year())]++
+			months[v.StarredAt.UTC().Format(growthLayout)]++
+		}
+		stale := !v.PushedAt.IsZero() && v.PushedAt.Before(staleBefore)
+		if v.Archived {
+			stats.Archived++
+		}
+		if stale {
+			stats.Stale++
+		}
+		if v.Archived || stale {
+			stats.ArchivedOrStale++
+		}
+		stargazers = append(stargazers, v.StargazersCount)
+	}
+
+	stats.Languages = sortCounts(languages)
+	stats.Owners = sortCounts(owners)
+	stats.TopOwners = stats.Owners
+	if top >= 0 && len(stats.TopOwners) > top {
+		stats.TopOwners = stats.TopOwners[:top]
+	}
+	stats.Licenses = sortCounts(licenses)
+	stats.Years = sortCountsByName(years)
+	stats.Growth = sortCountsByName(months)
+	total := 0
+	for i := range stats.Growth {
+		total += stats.Growth[i].Count
+		stats.Growth[i].Count = total
+	}
+	if stats.Total > 0 {
+		stats.ArchivedOrStalePercent = float64(stats.ArchivedOrStale) * 100 / float64(stats.Total)
+	}
+	stats.MedianStargazers = median(stargazers)
+	return stats
+}
+
+func sortCounts(m map[string]int) []Count {
+	counts := sortCountsByName(m)
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	return counts
+}
+
+func sortCountsByName(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, count := range m {
+		counts = append(counts, Count{Name: name, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+func median(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[middle])
+	}
+	return float64(sorted[middle-1]+sorted[middle]) / 2
+}
+
+// WriteTable prints the stats as aligned terminal tables
+func (self Stats) WriteTable(wr io.Writer) error {
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Total\t%d\n", self.Total)
+	fmt.Fprintf(tw, "Archived\t%d\n", self.Archived)
+	fmt.Fprintf(tw, "Stale\t%d\n", self.Stale)
+	fmt.Fprintf(tw, "Archived or stale\t%.1f%%\n", self.ArchivedOrStalePercent)
+	fmt.Fprintf(tw, "Median stargazers\t%g\n", self.MedianStargazers)
+	for _, section := range []struct {
+		Title  string
+		Counts []Count
+	}{
+		{"Language", self.Languages},
+		{"Top owner", self.TopOwners},
+		{"License", self.Licenses},
+		{"Year starred", self.Years},
+		{"Month (total)", self.Growth},
+	} {
+		fmt.Fprintf(tw, "\n%s\tCount\n", section.Title)
+		for _, count := range section.Counts {
+			fmt.Fprintf(tw, "%s\t%d\n", count.Name, count.Count)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStats(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	now = func() time.Time { return time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC) }
+
+	repos := Repos(testStarredRows())
+	extra := Repository{
+		FullName:        "victorspringer/old",
+		StargazersCount: 50,
+		PushedAt:        time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		StarredAt:       time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC),
+	}
+	extra.Owner.Login = "victorspringer"
+	repos = append(repos, extra)
+
+	stats := ComputeStats(repos, 1, DefaultStaleAfter)
+	require.Equal(3, stats.Total)
+	require.Equal([]Count{{"Go", 1}, {"JavaScript", 1}, {Others, 1}}, stats.Languages)
+	require.Equal([]Count{{"victorspringer", 2}, {"stefanwuthrich", 1}}, stats.Owners)
+	require.Equal([]Count{{"victorspringer", 2}}, stats.TopOwners)
+	require.Equal([]Count{{NoLicense, 2}, {"MIT", 1}}, stats.Licenses)
+	require.Equal([]Count{{"2020", 1}, {"2021", 2}}, stats.Years)
+	require.Equal([]Count{{"2020-12", 1}, {"2021-03", 3}}, stats.Growth)
+	require.Equal(1, stats.Archived)
+	require.Equal(1, stats.Stale)
+	require.Equal(2, stats.ArchivedOrStale)
+	require.InDelta(66.7, stats.ArchivedOrStalePercent, 0.1)
+	require.Equal(float64(50), stats.MedianStargazers)
+}
+
+func TestComputeStatsWithoutRepositories(t *testing.T) {
+	require := require.New(t)
+	stats := ComputeStats(nil, DefaultStatsTop, DefaultStaleAfter)
+	require.Equal(0, stats.Total)
+	require.Equal(float64(0), stats.ArchivedOrStalePercent)
+	require.Equal(float64(0), stats.MedianStargazers)
+}
+
+func TestMedian(t *testing.T) {
+	require := require.New(t)
+	require.Equal(float64(2), median([]int{3, 1, 2}))
+	require.Equal(2.5, median([]int{4, 1, 3, 2}))
+}
+
+func TestStatsWriteTable(t *testing.T) {
+	require := require.New(t)
+	stats := ComputeStats(Repos(testStarredRows()), DefaultStatsTop, DefaultStaleAfter)
+
+	var output strings.Builder
+	require.NoError(stats.WriteTable(&output))
+	require.Contains(output.String(), "Total              2\n")
+	require.Contains(output.String(), "\nLanguage    Count\nGo          1\nJavaScript  1\n")
+}
@@ -1,11 +1,19 @@
 package services
 
-import "time"
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type MarkDownRow struct {
 	Language string
 	Stars    string
 	Items    string
+	// Repos keeps the repositories behind Items for printers that need
+	// more than the pre-formatted markdown links.
+	Repos UserStarredRepositories
 }
 
 type MarkDownRepo struct {
@@ -14,7 +22,31 @@ type MarkDownRepo struct {
 	Language string
 }
 
-type UserStarredRepositories []struct {
+type UserStarredRepositories []Repository
+
+// repoFields renders repository fields by name, they are the csv columns
+// and the fields known to the where and groupby template functions
+var repoFields = map[string]func(Repository) string{
+	"full_name":  func(v Repository) string { return v.FullName },
+	"url":        func(v Repository) string { return v.HTMLURL },
+	"language":   func(v Repository) string { return v.Language },
+	"stars":      func(v Repository) string { return strconv.Itoa(v.StargazersCount) },
+	"forks":      func(v Repository) string { return strconv.Itoa(v.ForksCount) },
+	"license":    func(v Repository) string { return v.License.SpdxID },
+	"starred_at": func(v Repository) string { return formatFieldTime(v.StarredAt) },
+	"pushed_at":  func(v Repository) string { return formatFieldTime(v.PushedAt) },
+	"archived":   func(v Repository) string { return strconv.FormatBool(v.Archived) },
+	"topics":     func(v Repository) string { return strings.Join(v.Topics, " ") },
+}
+
+func formatFieldTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type Repository struct {
 	ID       int    `json:"id"`
 	NodeID   string `json:"node_id"`
 	Name     string `json:"name"`
@@ -109,13 +141,36 @@ type UserStarredRepositories []struct {
 		URL    string `json:"url"`
 		NodeID string `json:"node_id"`
 	} `json:"license"`
-	Forks         int    `json:"forks"`
-	OpenIssues    int    `json:"open_issues"`
-	Watchers      int    `json:"watchers"`
-	DefaultBranch string `json:"default_branch"`
+	Topics        []string `json:"topics"`
+	Forks         int      `json:"forks"`
+	OpenIssues    int      `json:"open_issues"`
+	Watchers      int      `json:"watchers"`
+	DefaultBranch string   `json:"default_branch"`
 	Permissions   struct {
 		Admin bool `json:"admin"`
 		Push  bool `json:"push"`
 		Pull  bool `json:"pull"`
 	} `json:"permissions"`
+	// StarredAt is only filled when the repository comes from the
+	// application/vnd.github.v3.star+json media type.
+	StarredAt time.Time `json:"starred_at"`
+}
+
+// UnmarshalJSON accepts both the plain repository payload and the star
+// payload, which wraps the repository as {"starred_at": ..., "repo": {...}}.
+func (r *Repository) UnmarshalJSON(b []byte) error {
+	type repository Repository
+	var starred struct {
+		StarredAt time.Time   `json:"starred_at"`
+		Repo      *repository `json:"repo"`
+	}
+	if err := json.Unmarshal(b, &starred); err != nil {
+		return err
+	}
+	if starred.Repo != nil {
+		*r = Repository(*starred.Repo)
+		r.StarredAt = starred.StarredAt
+		return nil
+	}
+	return json.Unmarshal(b, (*repository)(r))
 }
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	ErrorMissingLayout = "template has no %q block"
+	ErrorUnknownField  = "unknown field %s in type %s"
+	ErrorUnknownBlock  = "no such template %q"
+
+	// LayoutTemplate is the block every printer executes
+	LayoutTemplate = "layout"
+)
+
+// TemplateProblem is one finding of ValidateTemplate, Location is
+// "file:line:col" when known.
+type TemplateProblem struct {
+	Location string
+	Message  string
+}
+
+func (self TemplateProblem) String() string {
+	if self.Location == "" {
+		return self.Message
+	}
+	return self.Location + ": " + self.Message
+}
+
+// ValidateTemplate checks that tpl defines the layout block, statically
+// resolves every field it references against the row types, then executes
+// it against sample.
+func ValidateTemplate(tpl *template.Template, sample []MarkDownRow) []TemplateProblem {
+	layout := tpl.Lookup(LayoutTemplate)
+	if layout == nil || layout.Tree == nil {
+		return []TemplateProblem{{Message: fmt.Sprintf(ErrorMissingLayout, LayoutTemplate)}}
+	}
+
+	checker := &templateChecker{
+		tpl:       tpl,
+		funcs:     funcReturnTypes(TemplateFuncs()),
+		visited:   make(map[string]bool),
+		seen:      make(map[string]bool),
+		locations: make(map[string]bool),
+	}
+	checker.template(LayoutTemplate, reflect.TypeOf(sample))
+
+	if err := Print2Template(ioutil.Discard, tpl, sample); err != nil {
+		message := strings.TrimPrefix(err.Error(), "template: ")
+		// the static pass already reported unknown fields at this position
+		if !checker.reported(message) {
+			checker.problems = append(checker.problems, TemplateProblem{Message: message})
+		}
+	}
+	return checker.problems
+}
+
+func funcReturnTypes(funcs template.FuncMap) map[string]reflect.Type {
+	types := map[string]reflect.Type{
+		"len":   reflect.TypeOf(0),
+		"print": reflect.TypeOf(""),
+	}
+	for name, fn := range funcs {
+		if t := reflect.TypeOf(fn); t.Kind() == reflect.Func && t.NumOut() > 0 {
+			types[name] = t.Out(0)
+		}
+	}
+	return types
+}
+
+// templateChecker walks parse trees tracking the type of dot, a nil type
+// means unknown and disables checks below it.
+type templateChecker struct {
+	tpl       *template.Template
+	funcs     map[string]reflect.Type
+	visited   map[string]bool
+	seen      map[string]bool
+	locations map[string]bool
+	problems  []TemplateProblem
+}
+
+func (self *templateChecker) reported(message string) bool {
+	for location := range self.locations {
+		if strings.HasPrefix(message, location+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *templateChecker) problem(tree *parse.Tree, node parse.Node, message string) {
+	location, _ := tree.ErrorContext(node)
+	key := location + message
+	if self.seen[key] {
+		return
+	}
+	self.seen[key] = true
+	self.locations[location] = true
+	self.problems = append(self.problems, TemplateProblem{Location: location, Message: message})
+}
+
+func (self *templateChecker) template(name string, dot reflect.Type) {
+	key := name + "\x00" + fmt.Sprint(dot)
+	if self.visited[key] {
+		return
+	}
+	self.visited[key] = true
+	t := self.tpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return
+	}
+	self.walk(t.Tree, t.Tree.Root, dot)
+}
+
+func (self *templateChecker) walk(tree *parse.Tree, node parse.Node, dot reflect.Type) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			self.walk(tree, child, dot)
+		}
+	case *parse.ActionNode:
+		self.pipe(tree, n.Pipe, dot)
+	case *parse.IfNode:
+		self.pipe(tree, n.Pipe, dot)
+		self.walk(tree, n.List, dot)
+		self.walk(tree, n.ElseList, dot)
+	case *parse.RangeNode:
+		self.walk(tree, n.List, elemType(self.pipe(tree, n.Pipe, dot)))
+		self.walk(tree, n.ElseList, dot)
+	case *parse.WithNode:
+		self.walk(tree, n.List, self.pipe(tree, n.Pipe, dot))
+		self.walk(tree, n.ElseList, dot)
+	case *parse.TemplateNode:
+		if self.tpl.Lookup(n.Name) == nil {
+			self.problem(tree, n, fmt.Sprintf(ErrorUnknownBlock, n.Name))
+			return
+		}
+		self.template(n.Name, self.pipe(tree, n.Pipe, dot))
+	}
+}
+
+func (self *templateChecker) pipe(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type) reflect.Type {
+	if pipe == nil {
+		return nil
+	}
+	var typ reflect.Type
+	for _, cmd := range pipe.Cmds {
+		typ = self.command(tree, cmd, dot)
+	}
+	return typ
+}
+
+func (self *templateChecker) command(tree *parse.Tree, cmd *parse.CommandNode, dot reflect.Type) reflect.Type {
+	for _, arg := range cmd.Args[1:] {
+		self.arg(tree, arg, dot)
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return self.funcs[ident.Ident]
+	}
+	return self.arg(tree, cmd.Args[0], dot)
+}
+
+func (self *templateChecker) arg(tree *parse.Tree, node parse.Node, dot reflect.Type) reflect.Type {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return self.fields(tree, n, dot, n.Ident)
+	case *parse.ChainNode:
+		if pipe, ok := n.Node.(*parse.PipeNode); ok {
+			return self.fields(tree, n, self.pipe(tree, pipe, dot), n.Field)
+		}
+	case *parse.PipeNode:
+		return self.pipe(tree, n, dot)
+	}
+	return nil
+}
+
+func (self *templateChecker) fields(tree *parse.Tree, node parse.Node, typ reflect.Type, idents []string) reflect.Type {
+	for _, ident := range idents {
+		if typ == nil {
+			return nil
+		}
+		if method, ok := reflect.PtrTo(typ).MethodByName(ident); ok {
+			if method.Type.NumOut() == 0 {
+				return nil
+			}
+			typ = method.Type.Out(0)
+			continue
+		}
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Interface:
+			return nil
+		case reflect.Map:
+			typ = typ.Elem()
+			continue
+		case reflect.Struct:
+			if field, ok := typ.FieldByName(ident); ok && field.PkgPath == "" {
+				typ = field.Type
+				continue
+			}
+		}
+		self.problem(tree, node, fmt.Sprintf(ErrorUnknownField, ident, typeName(typ)))
+		return nil
+	}
+	return typ
+}
+
+func elemType(typ reflect.Type) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return typ.Elem()
+	}
+	return nil
+}
+
+// typeName keeps messages short for the anonymous structs of Repository
+func typeName(typ reflect.Type) string {
+	if typ.Kind() == reflect.Struct && typ.Name() == "" {
+		return "struct {...}"
+	}
+	return typ.String()
+}
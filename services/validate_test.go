@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestTemplate(require *require.Assertions, text string) *template.Template {
+	tpl, err := template.New("tpl.md").Funcs(TemplateFuncs()).Parse(text)
+	require.NoError(err)
+	return tpl
+}
+
+func problemStrings(problems []TemplateProblem) []string {
+	var s []string
+	for _, problem := range problems {
+		s = append(s, problem.String())
+	}
+	return s
+}
+
+func TestValidateTemplate(t *testing.T) {
+	require := require.New(t)
+	tpl := parseTestTemplate(require, `{{define "layout"}}{{range .}}{{.Language}}|{{.Stars}}
+{{range .Repos}}{{block "repo" .}}{{.FullName}} {{.Owner.Login}} {{.StarredAt.Format "2006"}}{{end}}{{end}}
+{{end}}{{range repos . | sortby "-stars"}}{{.License.SpdxID}}{{end}}{{end}}`)
+	require.Empty(ValidateTemplate(tpl, testStarredRows()))
+}
+
+func TestValidateTemplateWithUnknownFields(t *testing.T) {
+	require := require.New(t)
+	tpl := parseTestTemplate(require, `{{define "layout"}}{{range .}}{{.Languages}}
+{{range .Repos}}{{block "repo" .}}{{.Owner.Name}}{{end}}{{end}}
+{{with index . 0}}{{.Stars}}{{end}}{{end}}{{end}}`)
+	require.Equal([]string{
+		"tpl.md:1:32: unknown field Languages in type services.MarkDownRow",
+		"tpl.md:2:42: unknown field Name in type struct {...}",
+	}, problemStrings(ValidateTemplate(tpl, testStarredRows())))
+}
+
+func TestValidateTemplateWithoutLayout(t *testing.T) {
+	require := require.New(t)
+	tpl := parseTestTemplate(require, `{{define "table"}}{{end}}`)
+	require.Equal([]string{`template has no "layout" block`}, problemStrings(ValidateTemplate(tpl, testStarredRows())))
+}
+
+func TestValidateTemplateWithExecutionError(t *testing.T) {
+	require := require.New(t)
+	tpl := parseTestTemplate(require, `{{define "layout"}}{{range .}}{{humanize .Language}}{{end}}{{end}}`)
+	problems := problemStrings(ValidateTemplate(tpl, testStarredRows()))
+	require.Len(problems, 1)
+	require.True(strings.HasPrefix(problems[0], `tpl.md:1:32: executing "layout" at <humanize .Language>: error calling humanize: Go is not a number`), problems[0])
+}
+
+func TestValidateTemplateWithUnknownBlock(t *testing.T) {
+	require := require.New(t)
+	tpl := parseTestTemplate(require, `{{define "layout"}}{{template "rows" .}}{{end}}`)
+	problems := problemStrings(ValidateTemplate(tpl, testStarredRows()))
+	require.Equal(`tpl.md:1:30: no such template "rows"`, problems[0])
+}
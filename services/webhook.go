@@ -0,0 +1,107 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC of the body keyed
+	// with the webhook secret
+	SignatureHeader = "X-Hub-Signature-256"
+	// EventHeader names the event of a webhook delivery
+	EventHeader = "X-GitHub-Event"
+
+	// EventStar is sent with the created and deleted actions, EventWatch is
+	// its older form sent with the started action when a repository is
+	// starred
+	EventStar  = "star"
+	EventWatch = "watch"
+
+	ErrorSignature    = "Invalid " + SignatureHeader
+	ErrorWebhookEvent = "Unsupported event %q"
+	ErrorStarAction   = "Unsupported %s action %q"
+)
+
+// StarEvent is the payload of the star and watch events
+type StarEvent struct {
+	Event  string `json:"-"`
+	Action string `json:"action"`
+	// StarredAt is null when a star is deleted and missing from watch events
+	StarredAt  *time.Time `json:"starred_at"`
+	Repository Repository `json:"repository"`
+	Sender     struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// Signature is the X-Hub-Signature-256 value of body
+func Signature(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the X-Hub-Signature-256 value of body in constant
+// time
+func VerifySignature(secret []byte, body []byte, signature string) error {
+	if !hmac.Equal([]byte(Signature(secret, body)), []byte(signature)) {
+		return errors.New(ErrorSignature)
+	}
+	return nil
+}
+
+// ParseStarEvent parses the body of a star or watch event
+func ParseStarEvent(event string, body []byte) (StarEvent, error) {
+	var starEvent StarEvent
+	if event != EventStar && event != EventWatch {
+		return starEvent, fmt.Errorf(ErrorWebhookEvent, event)
+	}
+	if err := json.Unmarshal(body, &starEvent); err != nil {
+		return starEvent, err
+	}
+	starEvent.Event = event
+	switch {
+	case event == EventStar && (starEvent.Action == "created" || starEvent.Action == "deleted"):
+	case event == EventWatch && starEvent.Action == "started":
+	default:
+		return starEvent, fmt.Errorf(ErrorStarAction, event, starEvent.Action)
+	}
+	return starEvent, nil
+}
+
+// Starred tells whether the event stars or unstars the repository
+func (self StarEvent) Starred() bool {
+	return self.Action != "deleted"
+}
+
+// By tells whether userName sent the event
+func (self StarEvent) By(userName string) bool {
+	return strings.EqualFold(self.Sender.Login, userName)
+}
+
+// ApplyStarEvent returns a copy of repositories with the repository of the
+// event added or removed, a repository already starred is replaced
+func ApplyStarEvent(repositories UserStarredRepositories, event StarEvent) UserStarredRepositories {
+	applied := make(UserStarredRepositories, 0, len(repositories)+1)
+	for _, v := range repositories {
+		if v.ID != event.Repository.ID {
+			applied = append(applied, v)
+		}
+	}
+	if !event.Starred() {
+		return applied
+	}
+	repository := event.Repository
+	repository.StarredAt = now().UTC()
+	if event.StarredAt != nil {
+		repository.StarredAt = *event.StarredAt
+	}
+	return append(applied, repository)
+}
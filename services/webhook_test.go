@@ -0,0 +1,84 @@
+package services
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySignature(t *testing.T) {
+	require := require.New(t)
+	// the example of the GitHub documentation
+	body := []byte("Hello, World!")
+	secret := []byte("It's a Secret to Everybody")
+	require.Equal("sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", Signature(secret, body))
+	require.NoError(VerifySignature(secret, body, Signature(secret, body)))
+	require.EqualError(VerifySignature(secret, body, Signature([]byte("guess"), body)), ErrorSignature)
+	require.EqualError(VerifySignature(secret, body, ""), ErrorSignature)
+}
+
+func TestParseStarEvent(t *testing.T) {
+	require := require.New(t)
+	for file, expected := range map[string]struct {
+		Event   string
+		Starred bool
+	}{
+		"../mock_data/webhook_star_created.json":  {EventStar, true},
+		"../mock_data/webhook_star_deleted.json":  {EventStar, false},
+		"../mock_data/webhook_watch_started.json": {EventWatch, true},
+	} {
+		body, err := ioutil.ReadFile(file)
+		require.NoError(err)
+		event, err := ParseStarEvent(expected.Event, body)
+		require.NoError(err, file)
+		require.Equal(expected.Starred, event.Starred(), file)
+		require.True(event.By("alphawong"), file)
+		require.False(event.By("octocat"), file)
+	}
+
+	_, err := ParseStarEvent("push", []byte(`{}`))
+	require.EqualError(err, `Unsupported event "push"`)
+	_, err = ParseStarEvent(EventWatch, []byte(`{"action":"stopped"}`))
+	require.EqualError(err, `Unsupported watch action "stopped"`)
+	_, err = ParseStarEvent(EventStar, []byte(`{`))
+	require.Error(err)
+}
+
+func TestApplyStarEvent(t *testing.T) {
+	require := require.New(t)
+	defer func() {
+		now = time.Now
+	}()
+	now = func() time.Time { return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) }
+	repositories := Repos(testStarredRows())
+
+	body, err := ioutil.ReadFile("../mock_data/webhook_star_created.json")
+	require.NoError(err)
+	created, err := ParseStarEvent(EventStar, body)
+	require.NoError(err)
+	applied := ApplyStarEvent(repositories, created)
+	require.Len(applied, len(repositories)+1)
+	require.Equal("microsoft/vscode", applied[len(applied)-1].FullName)
+	require.Equal(time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC), applied[len(applied)-1].StarredAt)
+	// starring again replaces rather than duplicates
+	require.Len(ApplyStarEvent(applied, created), len(applied))
+
+	body, err = ioutil.ReadFile("../mock_data/webhook_watch_started.json")
+	require.NoError(err)
+	started, err := ParseStarEvent(EventWatch, body)
+	require.NoError(err)
+	applied = ApplyStarEvent(repositories, started)
+	require.Equal(now(), applied[len(applied)-1].StarredAt)
+
+	body, err = ioutil.ReadFile("../mock_data/webhook_star_deleted.json")
+	require.NoError(err)
+	deleted, err := ParseStarEvent(EventStar, body)
+	require.NoError(err)
+	// repositories are matched by ID, the one of the sample rows differs
+	require.Len(ApplyStarEvent(repositories, deleted), len(repositories))
+	deleted.Repository.ID = created.Repository.ID
+	applied = ApplyStarEvent(ApplyStarEvent(repositories, created), deleted)
+	require.Equal(repositories, applied)
+}
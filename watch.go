@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AlphaWong/Stars/services"
+)
+
+const (
+	// DefaultWatchInterval is how often watch runs without -cron
+	DefaultWatchInterval = time.Hour
+	// DefaultWatchBackoff is the wait after the first failed run, it doubles
+	// with every failure up to -max-backoff
+	DefaultWatchBackoff = time.Minute
+	// DefaultMaxBackoff caps the wait between failed runs
+	DefaultMaxBackoff = time.Hour
+	// WatchRateLimitReserve is how many requests must be left before a run
+	// starts, enough to fetch a few thousand stars
+	WatchRateLimitReserve = 50
+
+	// MetricsRoute serves the Prometheus metrics of watch and serve
+	MetricsRoute = "/metrics"
+
+	ErrorWatchSchedule = "Give either -every or -cron, not both"
+	ErrorWatchNoRun    = "The cron expression %q never runs"
+	ErrorWatchPanic    = "Run failed: %v"
+)
+
+// watchAfter waits between runs, tests replace it to run without waiting
+var watchAfter = time.After
+
+// runLogger writes one JSON object per line, it is safe for concurrent use
+type runLogger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newRunLogger(wr io.Writer) *runLogger {
+	return &runLogger{encoder: json.NewEncoder(wr)}
+}
+
+// Log writes fields along with the time, level and event
+func (self *runLogger) Log(level string, event string, fields map[string]interface{}) error {
+	entry := map[string]interface{}{
+		"time":  time.Now().UTC().Format(time.RFC3339),
+		"level": level,
+		"event": event,
+	}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.encoder.Encode(entry)
+}
+
+// watchRun is the outcome of one fetch and render
+type watchRun struct {
+	Stars   int
+	Changed bool
+	// digest identifies the fetched repositories for the formats which
+	// cannot be rendered into memory
+	digest [sha256.Size]byte
+}
+
+// watchCommand fetches and renders on a schedule until SIGTERM or SIGINT,
+// a run in progress is finished first. It writes the output only when it
+// changed, backs off after failures and waits for the rate limit to reset
+// when too few requests are left. Every run is logged as a JSON line.
+func watchCommand(config *BaseConfig, args []string, stdout io.Writer) (int, error) {
+	flags := newFlagSet("watch", config)
+	every := flags.Duration("every", DefaultWatchInterval, "run at this interval")
+	cron := flags.String("cron", "", `run on a cron expression instead of -every, e.g. "0 */6 * * *"`)
+	maxBackoff := flags.Duration("max-backoff", DefaultMaxBackoff, "longest wait between failed runs")
+	metricsAddr := flags.String("metrics-addr", "", "address serving Prometheus metrics on /metrics, empty serves none")
+	if err := flags.Parse(args); err != nil {
+		return ExitError, err
+	}
+	validConfig(config)
+
+	schedule, err := watchSchedule(flags, *every, *cron)
+	if err != nil {
+		return ExitError, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(MetricsRoute, services.DefaultMetrics)
+		metricsServer := &http.Server{Addr: *metricsAddr, Handler: mux}
+		listener, err := net.Listen("tcp", *metricsAddr)
+		if err != nil {
+			return ExitError, err
+		}
+		go metricsServer.Serve(listener)
+		defer metricsServer.Close()
+	}
+	return ExitOK, watch(ctx, config, schedule, *maxBackoff, stdout)
+}
+
+func watchSchedule(flags *flag.FlagSet, every time.Duration, cron string) (services.Schedule, error) {
+	if cron == "" {
+		return services.NewIntervalSchedule(every)
+	}
+	everySet := false
+	flags.Visit(func(f *flag.Flag) {
+		everySet = everySet || f.Name == "every"
+	})
+	if everySet {
+		return nil, errors.New(ErrorWatchSchedule)
+	}
+	schedule, err := services.ParseCron(cron, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf(ErrorWatchNoRun, cron)
+	}
+	return schedule, nil
+}
+
+// watch runs right away then on schedule until ctx is done
+func watch(ctx context.Context, config *BaseConfig, schedule services.Schedule, maxBackoff time.Duration, stdout io.Writer) error {
+	logger := newRunLogger(stdout)
+	var previous watchRun
+	failures := 0
+	for {
+		started := time.Now()
+		run, err := watchOnce(config, previous)
+		finished := time.Now()
+
+		var next time.Time
+		if err != nil {
+			failures++
+			services.DefaultMetrics.Add(services.MetricRunFailures, 1, "")
+			next = finished.Add(watchBackoff(failures, maxBackoff))
+			var limited *services.RateLimitError
+			if errors.As(err, &limited) && limited.Reset.After(next) {
+				next = limited.Reset
+			}
+			logger.Log("error", "run", map[string]interface{}{
+				"error":    err.Error(),
+				"failures": failures,
+				"next":     next.UTC().Format(time.RFC3339),
+			})
+		} else {
+			failures = 0
+			previous = run
+			services.DefaultMetrics.Set(services.MetricLastSuccess, float64(finished.Unix()), "")
+			next = schedule.Next(finished)
+			logger.Log("info", "run", map[string]interface{}{
+				"stars":       run.Stars,
+				"changed":     run.Changed,
+				"duration_ms": finished.Sub(started).Milliseconds(),
+				"next":        next.UTC().Format(time.RFC3339),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return logger.Log("info", "shutdown", nil)
+		case <-watchAfter(time.Until(next)):
+		}
+	}
+}
+
+// watchBackoff doubles the wait with every consecutive failure
+func watchBackoff(failures int, maxBackoff time.Duration) time.Duration {
+	backoff := DefaultWatchBackoff
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// watchOnce fetches and renders once, skipping the write when the rendered
+// output, or for the formats which cannot be rendered into memory the
+// fetched repositories, are the same as before.
+func watchOnce(config *BaseConfig, previous watchRun) (run watchRun, err error) {
+	repositories, err := fetchWithinRateLimit(config)
+	if err != nil {
+		return run, err
+	}
+	results := services.Repositories2Slice(repositories, services.GroupKeys[config.GroupBy])
+	run.Stars = len(repositories)
+	encoded, err := json.Marshal(repositories)
+	if err != nil {
+		return run, err
+	}
+	run.digest = sha256.Sum256(encoded)
+
+	printer, err := newPrinter(config)
+	if err != nil {
+		return run, err
+	}
+	printer = services.InstrumentPrinter(printer)
+	renderer, ok := printer.(services.Renderer)
+	if ok && config.SplitDir == "" {
+		rendered, err := renderer.Render(results)
+		if err != nil {
+			return run, err
+		}
+		current, err := ioutil.ReadFile(config.OutputPath)
+		if err != nil && !os.IsNotExist(err) {
+			return run, err
+		}
+		run.Changed = err != nil || !bytes.Equal(current, rendered)
+	} else {
+		run.Changed = run.digest != previous.digest
+	}
+	if !run.Changed {
+		return run, nil
+	}
+
+	if config.HistoryPath != "" {
+		if err := recordHistory(config, repositories); err != nil {
+			return run, err
+		}
+	}
+	if err := printer.PrintSlice(results); err != nil {
+		return run, err
+	}
+	return run, commitOutput(config, repositories)
+}
+
+// fetchWithinRateLimit fetches the starred repositories unless fewer than
+// WatchRateLimitReserve requests are left. The fetcher panics on a malformed
+// link header, a panic is returned as an error instead of stopping the long
+// running commands.
+func fetchWithinRateLimit(config *BaseConfig) (repositories services.UserStarredRepositories, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf(ErrorWatchPanic, r)
+		}
+	}()
+
+	fetcher, err := newFetcher(config)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := fetcher.GetRateLimit()
+	if err != nil {
+		return nil, err
+	}
+	if limit.Remaining < WatchRateLimitReserve {
+		return nil, &services.RateLimitError{Reset: limit.Reset}
+	}
+	return fetcher.GetUsersRepositories()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlphaWong/Stars/services"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	reset := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	remaining := []int{5000, 4990, 10}
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		services.GithubRateLimitURI,
+		func(req *http.Request) (*http.Response, error) {
+			body := fmt.Sprintf(`{"rate":{"limit":5000,"remaining":%d,"reset":%d}}`, remaining[0], reset.Unix())
+			remaining = remaining[1:]
+			return httpmock.NewStringResponse(http.StatusOK, body), nil
+		},
+	)
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+	defer os.Remove(outputFile.Name())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	defer func() { watchAfter = time.After }()
+	watchAfter = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		if len(waits) == 3 {
+			cancel()
+			return nil
+		}
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+
+	config := boot()
+	config.TemplatePack = "table"
+	config.OutputPath = outputFile.Name()
+	var logs bytes.Buffer
+	failures := services.DefaultMetrics.Value(services.MetricRunFailures, "")
+	require.NoError(watch(ctx, config, services.IntervalSchedule(time.Hour), time.Hour, &logs))
+	require.Equal(failures+1, services.DefaultMetrics.Value(services.MetricRunFailures, ""))
+	require.NotZero(services.DefaultMetrics.Value(services.MetricLastSuccess, ""))
+
+	var entries []map[string]interface{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var entry map[string]interface{}
+		require.NoError(decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	require.Len(entries, 4)
+	require.Equal("run", entries[0]["event"])
+	require.Equal(true, entries[0]["changed"])
+	require.Equal(float64(2), entries[0]["stars"])
+	require.Equal(false, entries[1]["changed"])
+	require.Equal("error", entries[2]["level"])
+	require.Equal("Rate limited until "+reset.Format(time.RFC3339), entries[2]["error"])
+	require.Equal(reset.UTC().Format(time.RFC3339), entries[2]["next"])
+	require.Equal("shutdown", entries[3]["event"])
+
+	require.InDelta(float64(time.Hour), float64(waits[0]), float64(time.Minute))
+	require.InDelta(float64(2*time.Hour), float64(waits[2]), float64(time.Minute))
+	actual, err := ioutil.ReadFile(outputFile.Name())
+	require.NoError(err)
+	require.Contains(string(actual), "victorspringer/http-cache")
+}
+
+func TestWatchSurvivesFailedPage(t *testing.T) {
+	require := require.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerStarredResponders(require)
+	registerRateLimitResponder()
+	page2Path, err := filepath.Abs("./mock_data/page_2.json")
+	require.NoError(err)
+	failed := false
+	httpmock.RegisterResponder(
+		http.MethodGet,
+		"https://api.github.com/users/alphawong/starred?page=2&per_page=100",
+		func(req *http.Request) (*http.Response, error) {
+			if !failed {
+				failed = true
+				return httpmock.NewStringResponse(http.StatusBadGateway, "bad gateway"), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, httpmock.File(page2Path).String()), nil
+		},
+	)
+
+	outputFile, err := ioutil.TempFile("", "out.*.md")
+	require.NoError(err)
+	require.NoError(outputFile.Close())
+	defer os.Remove(outputFile.Name())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	defer func() { watchAfter = time.After }()
+	watchAfter = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		if len(waits) == 2 {
+			cancel()
+			return nil
+		}
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+
+	config := boot()
+	config.TemplatePack = "table"
+	config.OutputPath = outputFile.Name()
+	var logs bytes.Buffer
+	require.NoError(watch(ctx, config, services.IntervalSchedule(time.Hour), time.Hour, &logs))
+
+	var entries []map[string]interface{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var entry map[string]interface{}
+		require.NoError(decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	require.Len(entries, 3)
+	require.Equal("error", entries[0]["level"])
+	require.Equal("Unexpected status 502 from https://api.github.com/users/alphawong/starred?page=2&per_page=100", entries[0]["error"])
+	require.Equal("info", entries[1]["level"])
+	require.Equal(float64(2), entries[1]["stars"])
+	require.InDelta(float64(DefaultWatchBackoff), float64(waits[0]), float64(time.Second))
+}
+
+func TestWatchBackoff(t *testing.T) {
+	require := require.New(t)
+	require.Equal(time.Minute, watchBackoff(1, time.Hour))
+	require.Equal(4*time.Minute, watchBackoff(3, time.Hour))
+	require.Equal(time.Hour, watchBackoff(20, time.Hour))
+	require.Equal(30*time.Second, watchBackoff(1, 30*time.Second))
+}
+
+func TestWatchSchedule(t *testing.T) {
+	require := require.New(t)
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	every := flags.Duration("every", DefaultWatchInterval, "")
+	cron := flags.String("cron", "", "")
+
+	require.NoError(flags.Parse([]string{"-cron", "0 */6 * * *"}))
+	schedule, err := watchSchedule(flags, *every, *cron)
+	require.NoError(err)
+	require.IsType(&services.CronSchedule{}, schedule)
+
+	require.NoError(flags.Parse([]string{"-every", "5m"}))
+	_, err = watchSchedule(flags, *every, *cron)
+	require.EqualError(err, ErrorWatchSchedule)
+
+	_, err = watchSchedule(flag.NewFlagSet("watch", flag.ContinueOnError), *every, "0 0 30 2 *")
+	require.EqualError(err, `The cron expression "0 0 30 2 *" never runs`)
+}
+
+func TestWatchCommandWithInvalidCron(t *testing.T) {
+	require := require.New(t)
+	require.Equal(ExitError, execute(boot(), []string{"watch", "-cron", "* * *"}, ioutil.Discard))
+}